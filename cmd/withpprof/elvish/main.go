@@ -7,10 +7,13 @@ import (
 
 	"src.elv.sh/pkg/buildinfo"
 	"src.elv.sh/pkg/daemon"
+	"src.elv.sh/pkg/elvfmt"
+	"src.elv.sh/pkg/lint"
 	"src.elv.sh/pkg/lsp"
 	"src.elv.sh/pkg/pprof"
 	"src.elv.sh/pkg/prog"
 	"src.elv.sh/pkg/shell"
+	"src.elv.sh/pkg/store"
 )
 
 func main() {
@@ -18,5 +21,6 @@ func main() {
 		[3]*os.File{os.Stdin, os.Stdout, os.Stderr}, os.Args,
 		prog.Composite(
 			&pprof.Program{}, &buildinfo.Program{}, &daemon.Program{}, &lsp.Program{},
+			&elvfmt.Program{}, &lint.Program{}, &store.Program{DefaultDBPath: shell.DBPath},
 			&shell.Program{ActivateDaemon: daemon.Activate})))
 }
@@ -50,6 +50,15 @@ type App interface {
 	// exit after the handler returns.
 	CommitCode()
 
+	// Suspend restores the terminal to its state before the app was set up,
+	// calls f, and sets up the terminal again. This lets f run programs that
+	// use the terminal, like text editors. The current content of the app is
+	// left on the terminal, and drawn again below after f returns.
+	//
+	// This method must be called when an event is being handled. If the app is
+	// not reading code, f is called directly.
+	Suspend(f func()) error
+
 	// Redraw requests a redraw. It never blocks and can be called regardless of
 	// whether the App is active or not.
 	Redraw()
@@ -63,6 +72,8 @@ type App interface {
 type app struct {
 	loop    *loop
 	reqRead chan struct{}
+	// Restores the terminal; only set when ReadCode is running.
+	restoreTTY func()
 
 	TTY               TTY
 	MaxHeight         func() int
@@ -72,6 +83,7 @@ type app struct {
 	Highlighter       Highlighter
 	Prompt            Prompt
 	RPrompt           Prompt
+	Autosuggester     Autosuggester
 	GlobalBindings    tk.Bindings
 
 	StateMutex sync.RWMutex
@@ -102,6 +114,7 @@ func NewApp(spec AppSpec) App {
 		Highlighter:       spec.Highlighter,
 		Prompt:            spec.Prompt,
 		RPrompt:           spec.RPrompt,
+		Autosuggester:     spec.Autosuggester,
 		GlobalBindings:    spec.GlobalBindings,
 		State:             spec.State,
 	}
@@ -123,6 +136,9 @@ func NewApp(spec AppSpec) App {
 	if a.RPrompt == nil {
 		a.RPrompt = NewConstPrompt(nil)
 	}
+	if a.Autosuggester == nil {
+		a.Autosuggester = dummyAutosuggester{}
+	}
 	if a.GlobalBindings == nil {
 		a.GlobalBindings = tk.DummyBindings{}
 	}
@@ -135,6 +151,9 @@ func NewApp(spec AppSpec) App {
 		Prompt:      a.Prompt.Get,
 		RPrompt:     a.RPrompt.Get,
 		QuotePaste:  spec.QuotePaste,
+		Autosuggest: a.Autosuggester.Get,
+		Indent:      spec.Indent,
+		AutoPair:    spec.AutoPair,
 		OnSubmit:    a.CommitCode,
 		State:       spec.CodeAreaState,
 
@@ -208,6 +227,7 @@ func (a *app) resetAllStates() {
 	a.MutateState(func(s *State) { *s = State{} })
 	a.codeArea.MutateState(
 		func(s *tk.CodeAreaState) { *s = tk.CodeAreaState{} })
+	a.codeArea.ResetUndo()
 }
 
 func (a *app) handle(e event) {
@@ -267,11 +287,13 @@ func (a *app) redraw(flag redrawFlag) {
 		a.codeArea.MutateState(func(s *tk.CodeAreaState) {
 			s.HideTips = true
 			s.HideRPrompt = hideRPrompt
+			s.HideSuggestion = true
 		})
 		bufMain := renderApp([]tk.Widget{a.codeArea /* no addon */}, width, height)
 		a.codeArea.MutateState(func(s *tk.CodeAreaState) {
 			s.HideTips = false
 			s.HideRPrompt = false
+			s.HideSuggestion = false
 		})
 		// Insert a newline after the buffer and position the cursor there. Do
 		// this with a Buffer that has one empty line.
@@ -415,7 +437,11 @@ func (a *app) ReadCode() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	defer restore()
+	a.restoreTTY = restore
+	defer func() {
+		a.restoreTTY()
+		a.restoreTTY = nil
+	}()
 
 	var wg sync.WaitGroup
 	defer wg.Wait()
@@ -454,7 +480,7 @@ func (a *app) ReadCode() (string, error) {
 		wg.Done()
 	}()
 
-	// Relay late updates from prompt, rprompt and highlighter.
+	// Relay late updates from prompt, rprompt, highlighter and autosuggester.
 	stopRelayLateUpdates := make(chan struct{})
 	defer close(stopRelayLateUpdates)
 	relayLateUpdates := func(ch <-chan struct{}) {
@@ -478,6 +504,7 @@ func (a *app) ReadCode() (string, error) {
 	relayLateUpdates(a.Prompt.LateUpdates())
 	relayLateUpdates(a.RPrompt.LateUpdates())
 	relayLateUpdates(a.Highlighter.LateUpdates())
+	relayLateUpdates(a.Autosuggester.LateUpdates())
 
 	// Trigger an initial prompt update.
 	a.triggerPrompts(true)
@@ -485,6 +512,26 @@ func (a *app) ReadCode() (string, error) {
 	return a.loop.Run()
 }
 
+func (a *app) Suspend(f func()) error {
+	if a.restoreTTY == nil {
+		f()
+		return nil
+	}
+	a.redraw(finalRedraw)
+	a.restoreTTY()
+	f()
+	restore, err := a.TTY.Setup()
+	if err != nil {
+		// Like when setting up the terminal in ReadCode fails, there's no
+		// point in continuing.
+		a.restoreTTY = func() {}
+		a.loop.Return("", err)
+		return err
+	}
+	a.restoreTTY = restore
+	return nil
+}
+
 func (a *app) Redraw() {
 	a.loop.Redraw(false)
 }
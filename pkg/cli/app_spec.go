@@ -20,6 +20,9 @@ type AppSpec struct {
 	GlobalBindings   tk.Bindings
 	CodeAreaBindings tk.Bindings
 	QuotePaste       func() bool
+	Autosuggester    Autosuggester
+	Indent           func() string
+	AutoPair         func() bool
 
 	SimpleAbbreviations    func(f func(abbr, full string))
 	CommandAbbreviations   func(f func(abbr, full string))
@@ -47,6 +50,22 @@ func (dummyHighlighter) Get(code string) (ui.Text, []ui.Text) {
 
 func (dummyHighlighter) LateUpdates() <-chan struct{} { return nil }
 
+// Autosuggester represents a source of suggestions for completing the code,
+// whose result can be delivered asynchronously.
+type Autosuggester interface {
+	// Get returns the suggested text to append to the code, or "" if there is
+	// no suggestion or it is not available yet.
+	Get(code string) string
+	// LateUpdates returns a channel for notifying late updates.
+	LateUpdates() <-chan struct{}
+}
+
+// An Autosuggester implementation that never suggests anything.
+type dummyAutosuggester struct{}
+
+func (dummyAutosuggester) Get(code string) string       { return "" }
+func (dummyAutosuggester) LateUpdates() <-chan struct{} { return nil }
+
 // Prompt represents a prompt whose result can be delivered asynchronously.
 type Prompt interface {
 	// Trigger requests a re-computation of the prompt. The force flag is set
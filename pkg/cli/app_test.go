@@ -487,6 +487,73 @@ func TestReadCode_NotifiesAboutUnboundKey(t *testing.T) {
 	f.TTY.TestMsg(t, ui.T("Unbound key: F1"))
 }
 
+// Suspending.
+
+func TestSuspend(t *testing.T) {
+	eventCh := make(chan string, 10)
+	var f *Fixture
+	f = Setup(func(spec *AppSpec, tty TTYCtrl) {
+		spec.GlobalBindings = tk.MapBindings{
+			term.K('X', ui.Ctrl): func(w tk.Widget) {
+				err := f.App.Suspend(func() { eventCh <- "called" })
+				if err != nil {
+					t.Errorf("Suspend returns error %v", err)
+				}
+				eventCh <- "returned"
+			},
+		}
+		tty.SetSetup(func() { eventCh <- "restored" }, nil)
+	})
+
+	f.TTY.Inject(term.K('X', ui.Ctrl))
+	wantEvents := []string{"restored", "called", "returned"}
+	for _, want := range wantEvents {
+		select {
+		case event := <-eventCh:
+			if event != want {
+				t.Fatalf("got event %q, want %q", event, want)
+			}
+		case <-time.After(testutil.Scaled(100 * time.Millisecond)):
+			t.Fatalf("event %q not seen", want)
+		}
+	}
+
+	// The terminal is set up again, and restored when ReadCode returns.
+	f.Stop()
+	if event := <-eventCh; event != "restored" {
+		t.Errorf("got event %q, want %q", event, "restored")
+	}
+}
+
+func TestSuspend_AbortsWhenTTYSetupReturnsError(t *testing.T) {
+	ttySetupErr := errors.New("a fake error")
+	var f *Fixture
+	f = Setup(func(spec *AppSpec, tty TTYCtrl) {
+		spec.GlobalBindings = tk.MapBindings{
+			term.K('X', ui.Ctrl): func(w tk.Widget) {
+				tty.SetSetup(func() {}, ttySetupErr)
+				f.App.Suspend(func() {})
+			},
+		}
+	})
+
+	f.TTY.Inject(term.K('X', ui.Ctrl))
+	_, err := f.Wait()
+
+	if err != ttySetupErr {
+		t.Errorf("ReadCode returns error %v, want %v", err, ttySetupErr)
+	}
+}
+
+func TestSuspend_CallsFunctionWhenNotReadingCode(t *testing.T) {
+	app := NewApp(AppSpec{})
+	called := false
+	app.Suspend(func() { called = true })
+	if !called {
+		t.Errorf("function not called")
+	}
+}
+
 // Misc features.
 
 func TestReadCode_TrimsBufferToMaxHeight(t *testing.T) {
@@ -8,6 +8,7 @@ import (
 type DB interface {
 	NextCmdSeq() (int, error)
 	AddCmd(cmd string) (int, error)
+	SetCmdMeta(seq int, meta storedefs.CmdMeta) error
 	CmdsWithSeq(from, upto int) ([]storedefs.Cmd, error)
 	PrevCmd(upto int, prefix string) (storedefs.Cmd, error)
 	NextCmd(from int, prefix string) (storedefs.Cmd, error)
@@ -27,6 +27,10 @@ func (s dbStore) AddCmd(cmd storedefs.Cmd) (int, error) {
 	return s.db.AddCmd(cmd.Text)
 }
 
+func (s dbStore) SetCmdMeta(seq int, meta storedefs.CmdMeta) error {
+	return s.db.SetCmdMeta(seq, meta)
+}
+
 func (s dbStore) Cursor(prefix string) Cursor {
 	return &dbStoreCursor{
 		s.db, prefix, s.upper, storedefs.Cmd{Seq: s.upper}, ErrEndOfHistory,
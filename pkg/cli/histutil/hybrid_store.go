@@ -25,6 +25,12 @@ func (s hybridStore) AddCmd(cmd storedefs.Cmd) (int, error) {
 	return seq, err
 }
 
+func (s hybridStore) SetCmdMeta(seq int, meta storedefs.CmdMeta) error {
+	err := s.shared.SetCmdMeta(seq, meta)
+	s.session.SetCmdMeta(seq, meta)
+	return err
+}
+
 func (s hybridStore) AllCmds() ([]storedefs.Cmd, error) {
 	shared, err := s.shared.AllCmds()
 	session, err2 := s.session.AllCmds()
@@ -29,6 +29,16 @@ func (s *memStore) AddCmd(cmd storedefs.Cmd) (int, error) {
 	return cmd.Seq, nil
 }
 
+func (s *memStore) SetCmdMeta(seq int, meta storedefs.CmdMeta) error {
+	for i := range s.cmds {
+		if s.cmds[i].Seq == seq {
+			s.cmds[i].Meta = &meta
+			return nil
+		}
+	}
+	return storedefs.ErrNoMatchingCmd
+}
+
 func (s *memStore) Cursor(prefix string) Cursor {
 	return &memStoreCursor{s.cmds, prefix, len(s.cmds)}
 }
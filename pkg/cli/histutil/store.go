@@ -12,6 +12,9 @@ type Store interface {
 	// Depending on the implementation, the Store might respect cmd.Seq and
 	// return it as is, or allocate another sequence number.
 	AddCmd(cmd storedefs.Cmd) (int, error)
+	// SetCmdMeta sets the metadata of the command with the given sequence
+	// number.
+	SetCmdMeta(seq int, meta storedefs.CmdMeta) error
 	// AllCmds returns all commands kept in the store.
 	AllCmds() ([]storedefs.Cmd, error)
 	// Cursor returns a cursor that iterating through commands with the given
@@ -23,6 +23,7 @@ func NewFaultyInMemoryDB(cmds ...string) FaultyInMemoryDB {
 // Implementation of FaultyInMemoryDB.
 type testDB struct {
 	cmds        []string
+	metas       map[int]storedefs.CmdMeta
 	oneOffError error
 }
 
@@ -48,6 +49,20 @@ func (s *testDB) AddCmd(cmd string) (int, error) {
 	return len(s.cmds) - 1, nil
 }
 
+func (s *testDB) SetCmdMeta(seq int, meta storedefs.CmdMeta) error {
+	if err := s.error(); err != nil {
+		return err
+	}
+	if seq < 0 || seq >= len(s.cmds) {
+		return storedefs.ErrNoMatchingCmd
+	}
+	if s.metas == nil {
+		s.metas = make(map[int]storedefs.CmdMeta)
+	}
+	s.metas[seq] = meta
+	return nil
+}
+
 func (s *testDB) CmdsWithSeq(from, upto int) ([]storedefs.Cmd, error) {
 	if err := s.error(); err != nil {
 		return nil, err
@@ -60,7 +75,11 @@ func (s *testDB) CmdsWithSeq(from, upto int) ([]storedefs.Cmd, error) {
 	}
 	var cmds []storedefs.Cmd
 	for i := from; i < upto; i++ {
-		cmds = append(cmds, storedefs.Cmd{Text: s.cmds[i], Seq: i})
+		cmd := storedefs.Cmd{Text: s.cmds[i], Seq: i}
+		if meta, ok := s.metas[i]; ok {
+			cmd.Meta = &meta
+		}
+		cmds = append(cmds, cmd)
 	}
 	return cmds, nil
 }
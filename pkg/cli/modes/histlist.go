@@ -2,6 +2,7 @@ package modes
 
 import (
 	"fmt"
+	"slices"
 
 	"src.elv.sh/pkg/cli"
 	"src.elv.sh/pkg/cli/tk"
@@ -21,6 +22,11 @@ type HistlistSpec struct {
 	Bindings tk.Bindings
 	// AllCmds is called to retrieve all commands.
 	AllCmds func() ([]storedefs.Cmd, error)
+	// If not nil, Search is called with the filter text and whether
+	// deduplication should be done to find commands, ordered from the best
+	// match to the worst match. It is used instead of AllCmds and Filter.Maker,
+	// and avoids loading the entire history into memory.
+	Search func(query string, dedup bool) ([]storedefs.Cmd, error)
 	// Dedup is called to determine whether deduplication should be done.
 	// Defaults to true if unset.
 	Dedup func() bool
@@ -36,22 +42,38 @@ func NewHistlist(app cli.App, spec HistlistSpec) (Histlist, error) {
 	if err != nil {
 		return nil, err
 	}
-	if spec.AllCmds == nil {
+	if spec.AllCmds == nil && spec.Search == nil {
 		return nil, errNoHistoryStore
 	}
 	if spec.Dedup == nil {
 		spec.Dedup = func() bool { return true }
 	}
 
-	cmds, err := spec.AllCmds()
-	if err != nil {
-		return nil, fmt.Errorf("db error: %v", err.Error())
-	}
-	last := map[string]int{}
-	for i, cmd := range cmds {
-		last[cmd.Text] = i
+	var filter func(p string) histlistItems
+	if spec.Search != nil {
+		filter = func(p string) histlistItems {
+			cmds, err := spec.Search(p, spec.Dedup())
+			if err != nil {
+				app.Notify(ErrorText(err))
+			}
+			// Put the best match at the bottom, closest to the code area.
+			slices.Reverse(cmds)
+			return histlistItems{cmds, nil}
+		}
+	} else {
+		cmds, err := spec.AllCmds()
+		if err != nil {
+			return nil, fmt.Errorf("db error: %v", err.Error())
+		}
+		last := map[string]int{}
+		for i, cmd := range cmds {
+			last[cmd.Text] = i
+		}
+		cmdItems := histlistItems{cmds, last}
+		filter = func(p string) histlistItems {
+			return cmdItems.filter(spec.Filter.makePredicate(p), spec.Dedup())
+		}
 	}
-	cmdItems := histlistItems{cmds, last}
 
 	w := tk.NewComboBox(tk.ComboBoxSpec{
 		CodeArea: tk.CodeAreaSpec{
@@ -81,7 +103,7 @@ func NewHistlist(app cli.App, spec HistlistSpec) (Histlist, error) {
 			},
 		},
 		OnFilter: func(w tk.ComboBox, p string) {
-			it := cmdItems.filter(spec.Filter.makePredicate(p), spec.Dedup())
+			it := filter(p)
 			w.ListBox().Reset(it, it.Len()-1)
 		},
 	})
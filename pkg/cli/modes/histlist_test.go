@@ -2,6 +2,7 @@ package modes
 
 import (
 	"regexp"
+	"slices"
 	"testing"
 
 	"src.elv.sh/pkg/cli"
@@ -152,6 +153,36 @@ func TestHistlist_CustomFilter(t *testing.T) {
 		"++++++++++++++++++++++++++++++++++++++++++++++++++")
 }
 
+func TestHistlist_Search(t *testing.T) {
+	f := Setup()
+	defer f.Stop()
+
+	var queries []string
+	search := func(query string, dedup bool) ([]storedefs.Cmd, error) {
+		queries = append(queries, query)
+		if query == "x" {
+			return nil, errMock
+		}
+		// Best match first.
+		return []storedefs.Cmd{{Text: "vi", Seq: 10}, {Text: "nvi", Seq: 3}}, nil
+	}
+	startHistlist(f.App, HistlistSpec{Search: search})
+	f.TTY.Inject(term.K('v'))
+	f.TestTTY(t,
+		"\n",
+		" HISTORY (dedup on)  v", Styles,
+		"********************  ", term.DotHere, "\n",
+		"   3 nvi\n",
+		"  10 vi                                           ", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++")
+	if want := []string{"", "v"}; !slices.Equal(queries, want) {
+		t.Errorf("got queries %q, want %q", queries, want)
+	}
+
+	f.TTY.Inject(term.K(ui.Backspace), term.K('x'))
+	f.TTY.TestMsg(t, ui.Concat(ui.T("error:", ui.FgRed), ui.T(" mock error")))
+}
+
 func startHistlist(app cli.App, spec HistlistSpec) {
 	w, err := NewHistlist(app, spec)
 	startMode(app, w, err)
@@ -22,6 +22,15 @@ type CodeArea interface {
 	MutateState(f func(*CodeAreaState))
 	// Submit triggers the OnSubmit callback.
 	Submit()
+	// Undo restores the buffer to what it was before the last edit, and
+	// returns whether there was an edit to undo. Consecutive edits from typing
+	// or deleting runes are undone together.
+	Undo() bool
+	// Redo reapplies the last edit reverted by Undo, and returns whether there
+	// was such an edit. Redoing is no longer possible after a new edit.
+	Redo() bool
+	// ResetUndo clears the history used by Undo and Redo.
+	ResetUndo()
 }
 
 // CodeAreaSpec specifies the configuration and initial state for CodeArea.
@@ -42,6 +51,19 @@ type CodeAreaSpec struct {
 	SimpleAbbreviations    func(f func(abbr, full string))
 	CommandAbbreviations   func(f func(abbr, full string))
 	SmallWordAbbreviations func(f func(abbr, full string))
+	// A function that returns a suggestion for the text following the code,
+	// which is shown after the code when the dot is at the end of the buffer.
+	// If this function is not given, the Widget does not show any suggestion.
+	Autosuggest func(code string) string
+	// A function that returns the string used for one level of indentation.
+	// When it returns a non-empty string, closing brackets typed at the start
+	// of a line are indented like the line of the opening bracket. If this
+	// function is not given, the Widget defaults to not adjusting indentation.
+	Indent func() string
+	// A function that returns whether to insert the closing bracket when an
+	// opening bracket is typed. If this function is not given, the Widget
+	// defaults to not pairing brackets.
+	AutoPair func() bool
 	// A function that returns whether pasted texts (from bracketed pastes)
 	// should be quoted. If this function is not given, the Widget defaults to
 	// not quoting pasted texts.
@@ -59,6 +81,20 @@ type CodeAreaState struct {
 	Pending     PendingCode
 	HideRPrompt bool
 	HideTips    bool
+	// Whether to hide the suggestion from Autosuggest.
+	HideSuggestion bool
+	// Part of the buffer to show as selected.
+	Selection Selection
+}
+
+// Selection represents a selected part of the code buffer. Nothing is selected
+// when From >= To, like the zero value.
+type Selection struct {
+	// Beginning index of the selected text, as a byte index into
+	// Buffer.Content.
+	From int
+	// End index of the selected text, as a byte index into Buffer.Content.
+	To int
 }
 
 // CodeBuffer represents the buffer of the CodeArea widget.
@@ -110,6 +146,8 @@ type codeArea struct {
 	pasting bool
 	// Buffer for keeping Pasted text during bracketed pasting.
 	pasteBuffer bytes.Buffer
+	// Undo and redo histories. Protected by StateMutex.
+	history undoHistory
 }
 
 // NewCodeArea creates a new CodeArea from the given spec.
@@ -135,6 +173,15 @@ func NewCodeArea(spec CodeAreaSpec) CodeArea {
 	if spec.SmallWordAbbreviations == nil {
 		spec.SmallWordAbbreviations = func(func(a, f string)) {}
 	}
+	if spec.Autosuggest == nil {
+		spec.Autosuggest = func(string) string { return "" }
+	}
+	if spec.Indent == nil {
+		spec.Indent = func() string { return "" }
+	}
+	if spec.AutoPair == nil {
+		spec.AutoPair = func() bool { return false }
+	}
 	if spec.QuotePaste == nil {
 		spec.QuotePaste = func() bool { return false }
 	}
@@ -181,9 +228,15 @@ func (w *codeArea) Handle(event term.Event) bool {
 }
 
 func (w *codeArea) MutateState(f func(*CodeAreaState)) {
+	w.mutateState(otherEdit, f)
+}
+
+func (w *codeArea) mutateState(kind editKind, f func(*CodeAreaState)) {
 	w.StateMutex.Lock()
 	defer w.StateMutex.Unlock()
+	before := w.State.Buffer
 	f(&w.State)
+	w.history.record(kind, before, w.State.Buffer)
 }
 
 func (w *codeArea) CopyState() CodeAreaState {
@@ -357,7 +410,7 @@ func (w *codeArea) handleKeyEvent(key ui.Key) bool {
 		return true
 	case ui.K(ui.Backspace), ui.K('H', ui.Ctrl):
 		w.resetInserts()
-		w.MutateState(func(s *CodeAreaState) {
+		w.mutateState(backspaceEdit, func(s *CodeAreaState) {
 			c := &s.Buffer
 			// Remove the last rune.
 			_, chop := utf8.DecodeLastRuneInString(c.Content[:c.Dot])
@@ -379,6 +432,8 @@ func (w *codeArea) handleKeyEvent(key ui.Key) bool {
 			// reset the state.
 			w.resetInserts()
 		}
+		before := w.State.Buffer
+		defer func() { w.history.record(typingEdit, before, w.State.Buffer) }()
 		s := string(key.Rune)
 		w.State.Buffer.InsertAtDot(s)
 		w.inserts += s
@@ -388,6 +443,9 @@ func (w *codeArea) handleKeyEvent(key ui.Key) bool {
 		}
 		w.expandSimpleAbbr()
 		w.expandSmallWordAbbr(key.Rune, CategorizeSmallWord)
+		if w.handleBracket(key.Rune) {
+			w.resetInserts()
+		}
 		return true
 	}
 }
@@ -0,0 +1,110 @@
+package tk
+
+import (
+	"strings"
+
+	"src.elv.sh/pkg/parse/parseutil"
+)
+
+// InsertNewline inserts a newline at the dot.
+//
+// If indent is not empty, the new line is indented by one more level of indent
+// than the line of the innermost bracket enclosing the dot. If the closing
+// bracket follows the dot on the same line, it is moved to a line of its own,
+// indented like the line of the opening bracket.
+//
+// No indentation is done if the dot is inside a string literal, since it would
+// become part of the string.
+func (c *CodeBuffer) InsertNewline(indent string) {
+	if indent == "" || parseutil.InStringLiteral(c.Content, c.Dot) {
+		c.InsertAtDot("\n")
+		return
+	}
+	b, ok := parseutil.EnclosingBracket(parseutil.Brackets(c.Content), c.Dot)
+	if !ok {
+		c.InsertAtDot("\n")
+		return
+	}
+	base := parseutil.LineIndent(c.Content, b.Open)
+	before := strings.TrimRight(c.Content[:c.Dot], " \t")
+	after := c.Content[c.Dot:]
+	if b.Close >= c.Dot && strings.Trim(c.Content[c.Dot:b.Close], " \t") == "" {
+		// The dot is just before the closing bracket, like in "{|}".
+		*c = CodeBuffer{
+			Content: before + "\n" + base + indent + "\n" + base + c.Content[b.Close:],
+			Dot:     len(before) + 1 + len(base) + len(indent),
+		}
+		return
+	}
+	after = strings.TrimLeft(after, " \t")
+	*c = CodeBuffer{
+		Content: before + "\n" + base + indent + after,
+		Dot:     len(before) + 1 + len(base) + len(indent),
+	}
+}
+
+var closingBrackets = map[rune]rune{'{': '}', '[': ']', '(': ')'}
+
+func isClosingBracket(r rune) bool {
+	return r == '}' || r == ']' || r == ')'
+}
+
+// Called after a rune r has been inserted before the dot, to pair brackets and
+// adjust the indentation of closing brackets. Returns whether the buffer has
+// been changed. This function assumes the state mutex is held.
+func (w *codeArea) handleBracket(r rune) bool {
+	buf := &w.State.Buffer
+	if !strings.HasSuffix(buf.Content[:buf.Dot], string(r)) {
+		// The rune has been changed by abbreviation expansion.
+		return false
+	}
+	pos := buf.Dot - 1
+	autoPair := w.AutoPair()
+	switch {
+	case autoPair && closingBrackets[r] != 0:
+		next := buf.Content[buf.Dot:]
+		if next != "" && !strings.ContainsAny(next[:1], " \t\n}])") {
+			// Only pair brackets when nothing is immediately after the dot,
+			// to avoid getting in the way when editing existing code.
+			return false
+		}
+		for _, b := range parseutil.Brackets(buf.Content) {
+			if b.Body == buf.Dot {
+				// Only pair brackets that are actually brackets, and not part
+				// of a string literal or a comment.
+				buf.Content = buf.Content[:buf.Dot] + string(closingBrackets[r]) + next
+				return true
+			}
+		}
+	case isClosingBracket(r):
+		if autoPair && strings.HasPrefix(buf.Content[buf.Dot:], string(r)) {
+			// If the next rune is the closing bracket of a bracket, type
+			// through it instead of inserting another one.
+			old := buf.Content[:pos] + buf.Content[buf.Dot:]
+			for _, b := range parseutil.Brackets(old) {
+				if b.Close == pos {
+					*buf = CodeBuffer{Content: old, Dot: pos + 1}
+					return true
+				}
+			}
+		}
+		indent := w.Indent()
+		start := parseutil.LineStart(buf.Content, pos)
+		if indent == "" || strings.Trim(buf.Content[start:pos], " \t") != "" {
+			return false
+		}
+		// The closing bracket is the first thing on the line; indent it like
+		// the line of the opening bracket.
+		for _, b := range parseutil.Brackets(buf.Content) {
+			if b.Close == pos {
+				base := parseutil.LineIndent(buf.Content, b.Open)
+				*buf = CodeBuffer{
+					Content: buf.Content[:start] + base + buf.Content[pos:],
+					Dot:     start + len(base) + 1,
+				}
+				return true
+			}
+		}
+	}
+	return false
+}
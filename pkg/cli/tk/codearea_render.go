@@ -8,14 +8,19 @@ import (
 
 // View model, calculated from State and used for rendering.
 type view struct {
-	prompt  ui.Text
-	rprompt ui.Text
-	code    ui.Text
-	dot     int
-	tips    []ui.Text
+	prompt     ui.Text
+	rprompt    ui.Text
+	code       ui.Text
+	dot        int
+	suggestion ui.Text
+	tips       []ui.Text
 }
 
-var stylingForPending = ui.Underlined
+var (
+	stylingForPending    = ui.Underlined
+	stylingForSuggestion = ui.Dim
+	stylingForSelection  = ui.Inverse
+)
 
 func getView(w *codeArea) *view {
 	s := w.CopyState()
@@ -24,6 +29,14 @@ func getView(w *codeArea) *view {
 	if s.HideTips {
 		errors = nil
 	}
+	if sel := s.Selection; pFrom == pTo && sel.From < sel.To &&
+		sel.From >= 0 && sel.To <= len(code.Content) {
+		// Apply stylingForSelection to [sel.From, sel.To). This is not done
+		// when there is pending code, which may shift the selected text.
+		parts := styledCode.Partition(sel.From, sel.To)
+		selected := ui.StyleText(parts[1], stylingForSelection)
+		styledCode = ui.Concat(parts[0], selected, parts[2])
+	}
 	if pFrom < pTo {
 		// Apply stylingForPending to [pFrom, pTo)
 		parts := styledCode.Partition(pFrom, pTo)
@@ -31,12 +44,23 @@ func getView(w *codeArea) *view {
 		styledCode = ui.Concat(parts[0], pending, parts[2])
 	}
 
+	var suggestion ui.Text
+	// Only show suggestions when the dot is at the end of the buffer and there
+	// is no pending code, so that the suggestion is always a continuation of
+	// what the user is typing.
+	if !s.HideSuggestion && s.Pending == (PendingCode{}) &&
+		code.Content != "" && code.Dot == len(code.Content) {
+		if text := w.Autosuggest(code.Content); text != "" {
+			suggestion = ui.T(text, stylingForSuggestion)
+		}
+	}
+
 	var rprompt ui.Text
 	if !s.HideRPrompt {
 		rprompt = w.RPrompt()
 	}
 
-	return &view{w.Prompt(), rprompt, styledCode, code.Dot, errors}
+	return &view{w.Prompt(), rprompt, styledCode, code.Dot, suggestion, errors}
 }
 
 func patchPending(c CodeBuffer, p PendingCode) (CodeBuffer, int, int) {
@@ -76,7 +100,8 @@ func renderView(v *view, buf *term.BufferBuilder) {
 	buf.
 		WriteStyled(parts[0]).
 		SetDotHere().
-		WriteStyled(parts[1])
+		WriteStyled(parts[1]).
+		WriteStyled(v.suggestion)
 
 	buf.EagerWrap = false
 	buf.Indent = 0
@@ -125,6 +125,74 @@ var codeAreaRenderTests = []renderTest{
 		Width: 10, Height: 24,
 		Want: bb(10).Write("> code").SetDotHere(),
 	},
+	{
+		Name: "suggestion",
+		Given: NewCodeArea(CodeAreaSpec{
+			Prompt:      p(ui.T("> ")),
+			Autosuggest: func(code string) string { return "ion" },
+			State:       CodeAreaState{Buffer: CodeBuffer{Content: "sugg", Dot: 4}},
+		}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("> sugg").SetDotHere().WriteStringSGR("ion", "2"),
+	},
+	{
+		Name: "no suggestion when dot is not at end",
+		Given: NewCodeArea(CodeAreaSpec{
+			Autosuggest: func(code string) string { return "ion" },
+			State:       CodeAreaState{Buffer: CodeBuffer{Content: "sugg", Dot: 2}},
+		}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("su").SetDotHere().Write("gg"),
+	},
+	{
+		Name: "no suggestion when buffer is empty",
+		Given: NewCodeArea(CodeAreaSpec{
+			Autosuggest: func(code string) string { return "ion" },
+		}),
+		Width: 10, Height: 24,
+		Want: bb(10).SetDotHere(),
+	},
+	{
+		Name: "no suggestion when there is pending code",
+		Given: NewCodeArea(CodeAreaSpec{
+			Autosuggest: func(code string) string { return "ion" },
+			State: CodeAreaState{
+				Buffer:  CodeBuffer{Content: "sugg", Dot: 4},
+				Pending: PendingCode{From: 4, To: 4, Content: "x"},
+			},
+		}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("sugg").WriteStringSGR("x", "4").SetDotHere(),
+	},
+	{
+		Name: "hiding suggestion",
+		Given: NewCodeArea(CodeAreaSpec{
+			Autosuggest: func(code string) string { return "ion" },
+			State: CodeAreaState{
+				Buffer: CodeBuffer{Content: "sugg", Dot: 4}, HideSuggestion: true,
+			},
+		}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("sugg").SetDotHere(),
+	},
+	{
+		Name: "selection",
+		Given: NewCodeArea(CodeAreaSpec{State: CodeAreaState{
+			Buffer:    CodeBuffer{Content: "code", Dot: 1},
+			Selection: Selection{From: 1, To: 3},
+		}}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("c").SetDotHere().WriteStringSGR("od", "7").Write("e"),
+	},
+	{
+		Name: "invalid selection",
+		Given: NewCodeArea(CodeAreaSpec{State: CodeAreaState{
+			Buffer:    CodeBuffer{Content: "code", Dot: 4},
+			Selection: Selection{From: 1, To: 5},
+		}}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("code").SetDotHere(),
+	},
 	{
 		Name: "pending code inserting at the dot",
 		Given: NewCodeArea(CodeAreaSpec{State: CodeAreaState{
@@ -463,6 +531,72 @@ var codeAreaHandleTests = []handleTest{
 		},
 		WantNewState: CodeAreaState{Buffer: CodeBuffer{Content: "\n", Dot: 1}},
 	},
+	{
+		Name: "closing bracket at start of line is dedented",
+		Given: NewCodeArea(CodeAreaSpec{
+			Indent: func() string { return "  " },
+			State: CodeAreaState{Buffer: CodeBuffer{
+				Content: "  f {\n    echo\n    ", Dot: 19}},
+		}),
+		Events: []term.Event{term.K('}')},
+		WantNewState: CodeAreaState{Buffer: CodeBuffer{
+			Content: "  f {\n    echo\n  }", Dot: 18}},
+	},
+	{
+		Name: "closing bracket after code is not dedented",
+		Given: NewCodeArea(CodeAreaSpec{
+			Indent: func() string { return "  " },
+			State: CodeAreaState{Buffer: CodeBuffer{
+				Content: "f {\n  echo ", Dot: 11}},
+		}),
+		Events: []term.Event{term.K('}')},
+		WantNewState: CodeAreaState{Buffer: CodeBuffer{
+			Content: "f {\n  echo }", Dot: 12}},
+	},
+	{
+		Name: "closing bracket is not dedented when indentation is disabled",
+		Given: NewCodeArea(CodeAreaSpec{
+			State: CodeAreaState{Buffer: CodeBuffer{
+				Content: "f {\n    ", Dot: 8}},
+		}),
+		Events: []term.Event{term.K('}')},
+		WantNewState: CodeAreaState{Buffer: CodeBuffer{
+			Content: "f {\n    }", Dot: 9}},
+	},
+	{
+		Name:   "bracket pairing",
+		Given:  NewCodeArea(CodeAreaSpec{AutoPair: func() bool { return true }}),
+		Events: []term.Event{term.K('x'), term.K(' '), term.K('['), term.K('(')},
+		WantNewState: CodeAreaState{Buffer: CodeBuffer{
+			Content: "x [()]", Dot: 4}},
+	},
+	{
+		Name:   "bracket pairing typing through closing brackets",
+		Given:  NewCodeArea(CodeAreaSpec{AutoPair: func() bool { return true }}),
+		Events: []term.Event{term.K('x'), term.K(' '), term.K('['), term.K('a'), term.K(']')},
+		WantNewState: CodeAreaState{Buffer: CodeBuffer{
+			Content: "x [a]", Dot: 5}},
+	},
+	{
+		Name: "bracket pairing not done in string literals",
+		Given: NewCodeArea(CodeAreaSpec{
+			AutoPair: func() bool { return true },
+			State:    CodeAreaState{Buffer: CodeBuffer{Content: "x 'a", Dot: 4}},
+		}),
+		Events: []term.Event{term.K('[')},
+		WantNewState: CodeAreaState{Buffer: CodeBuffer{
+			Content: "x 'a[", Dot: 5}},
+	},
+	{
+		Name: "bracket pairing not done before code",
+		Given: NewCodeArea(CodeAreaSpec{
+			AutoPair: func() bool { return true },
+			State:    CodeAreaState{Buffer: CodeBuffer{Content: "x a", Dot: 2}},
+		}),
+		Events: []term.Event{term.K('[')},
+		WantNewState: CodeAreaState{Buffer: CodeBuffer{
+			Content: "x [a", Dot: 3}},
+	},
 }
 
 func TestCodeArea_Handle(t *testing.T) {
@@ -548,3 +682,89 @@ func TestCodeAreaState_ApplyPending(t *testing.T) {
 			Rets(CodeAreaState{Buffer: CodeBuffer{Content: "x", Dot: 1}, HideRPrompt: true}),
 	)
 }
+
+func TestCodeBuffer_InsertNewline(t *testing.T) {
+	insertNewline := func(content string, dot int, indent string) CodeBuffer {
+		c := CodeBuffer{Content: content, Dot: dot}
+		c.InsertNewline(indent)
+		return c
+	}
+	tt.Test(t, tt.Fn(insertNewline).Named("insertNewline"),
+		Args("echo", 4, "  ").Rets(CodeBuffer{Content: "echo\n", Dot: 5}),
+		Args("f {", 3, "  ").Rets(CodeBuffer{Content: "f {\n  ", Dot: 6}),
+		Args("f {", 3, "").Rets(CodeBuffer{Content: "f {\n", Dot: 4}),
+		Args("  f { echo", 10, "\t").Rets(CodeBuffer{Content: "  f { echo\n  \t", Dot: 14}),
+		Args("f { ", 4, "  ").Rets(CodeBuffer{Content: "f {\n  ", Dot: 6}),
+		Args("f {}", 3, "  ").Rets(CodeBuffer{Content: "f {\n  \n}", Dot: 6}),
+		Args("f [ ] x", 4, "  ").Rets(CodeBuffer{Content: "f [\n  \n] x", Dot: 6}),
+		Args("f [a b]", 4, "  ").Rets(CodeBuffer{Content: "f [a\n  b]", Dot: 7}),
+		Args("f 'a{", 5, "  ").Rets(CodeBuffer{Content: "f 'a{\n", Dot: 6}),
+		Args("f { echo 'a", 11, "  ").Rets(CodeBuffer{Content: "f { echo 'a\n", Dot: 12}),
+		Args("f { echo \"a b\" }", 11, "  ").Rets(CodeBuffer{Content: "f { echo \"a\n b\" }", Dot: 12}),
+	)
+}
+
+func TestCodeArea_Undo(t *testing.T) {
+	w := NewCodeArea(CodeAreaSpec{})
+	buffer := func() CodeBuffer { return w.CopyState().Buffer }
+	wantBuffer := func(want CodeBuffer) {
+		t.Helper()
+		if got := buffer(); got != want {
+			t.Errorf("got buffer %v, want %v", got, want)
+		}
+	}
+
+	if w.Undo() {
+		t.Errorf("Undo returned true with no edits")
+	}
+	// Typed runes are grouped.
+	handleAll(w, term.K('e'), term.K('c'), term.K('h'), term.K('o'), term.K(' '))
+	// Moving the dot is not an edit, but ends the group.
+	w.MutateState(func(s *CodeAreaState) { s.Buffer.Dot = 4 })
+	w.MutateState(func(s *CodeAreaState) { s.Buffer.Dot = 5 })
+	handleAll(w, term.K('x'), term.K('y'))
+	// Deleted runes are grouped.
+	handleAll(w, term.K(ui.Backspace), term.K(ui.Backspace))
+	// Other edits are never grouped.
+	w.MutateState(func(s *CodeAreaState) { s.Buffer.InsertAtDot("a") })
+	w.MutateState(func(s *CodeAreaState) { s.Buffer.InsertAtDot("b") })
+	wantBuffer(CodeBuffer{"echo ab", 7})
+
+	w.Undo()
+	wantBuffer(CodeBuffer{"echo a", 6})
+	w.Undo()
+	wantBuffer(CodeBuffer{"echo ", 5})
+	w.Undo()
+	wantBuffer(CodeBuffer{"echo xy", 7})
+	w.Undo()
+	wantBuffer(CodeBuffer{"echo ", 5})
+	w.Undo()
+	wantBuffer(CodeBuffer{"", 0})
+	if w.Undo() {
+		t.Errorf("Undo returned true with all edits undone")
+	}
+
+	w.Redo()
+	wantBuffer(CodeBuffer{"echo ", 5})
+	w.Redo()
+	wantBuffer(CodeBuffer{"echo xy", 7})
+	// Typing after undoing or redoing starts a new group, and makes redoing no
+	// longer possible.
+	handleAll(w, term.K('z'))
+	if w.Redo() {
+		t.Errorf("Redo returned true after a new edit")
+	}
+	w.Undo()
+	wantBuffer(CodeBuffer{"echo xy", 7})
+
+	w.ResetUndo()
+	if w.Undo() {
+		t.Errorf("Undo returned true after ResetUndo")
+	}
+}
+
+func handleAll(w Widget, events ...term.Event) {
+	for _, event := range events {
+		w.Handle(event)
+	}
+}
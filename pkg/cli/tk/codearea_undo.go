@@ -0,0 +1,85 @@
+package tk
+
+// Kinds of edits, used for grouping consecutive edits into one undo step.
+type editKind int
+
+const (
+	// An edit that is never grouped with other edits, such as a kill or the
+	// acceptance of a completion.
+	otherEdit editKind = iota
+	// Inserting a rune by typing it.
+	typingEdit
+	// Deleting a rune with Backspace.
+	backspaceEdit
+)
+
+// Undo and redo histories of the code buffer.
+type undoHistory struct {
+	// Buffers before each undoable step, the last one being the latest.
+	undo []CodeBuffer
+	// Buffers before each undone step, the last one being the latest.
+	redo []CodeBuffer
+	// The kind of the last recorded edit, and the buffer after it. Used for
+	// grouping consecutive edits of the same kind.
+	lastKind   editKind
+	lastBuffer CodeBuffer
+}
+
+// Records an edit that changed the buffer from before to after. Changes that
+// only move the dot are not recorded, but end the current group of edits.
+func (h *undoHistory) record(kind editKind, before, after CodeBuffer) {
+	if before.Content == after.Content {
+		if before.Dot != after.Dot {
+			h.lastKind = otherEdit
+		}
+		return
+	}
+	if kind == otherEdit || kind != h.lastKind || before != h.lastBuffer {
+		h.undo = append(h.undo, before)
+	}
+	h.redo = nil
+	h.lastKind, h.lastBuffer = kind, after
+}
+
+// Moves the latest buffer from one stack to the other, putting cur in its
+// place. Returns the buffer and whether there was one.
+func (h *undoHistory) swap(from, to *[]CodeBuffer, cur CodeBuffer) (CodeBuffer, bool) {
+	if len(*from) == 0 {
+		return CodeBuffer{}, false
+	}
+	buf := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, cur)
+	// Don't group the next edit with the one before the undo or redo.
+	h.lastKind = otherEdit
+	return buf, true
+}
+
+func (w *codeArea) Undo() bool {
+	return w.undoOrRedo(func(h *undoHistory, cur CodeBuffer) (CodeBuffer, bool) {
+		return h.swap(&h.undo, &h.redo, cur)
+	})
+}
+
+func (w *codeArea) Redo() bool {
+	return w.undoOrRedo(func(h *undoHistory, cur CodeBuffer) (CodeBuffer, bool) {
+		return h.swap(&h.redo, &h.undo, cur)
+	})
+}
+
+func (w *codeArea) undoOrRedo(f func(*undoHistory, CodeBuffer) (CodeBuffer, bool)) bool {
+	w.StateMutex.Lock()
+	defer w.StateMutex.Unlock()
+	buf, ok := f(&w.history, w.State.Buffer)
+	if ok {
+		w.State.Buffer = buf
+		w.resetInserts()
+	}
+	return ok
+}
+
+func (w *codeArea) ResetUndo() {
+	w.StateMutex.Lock()
+	defer w.StateMutex.Unlock()
+	w.history = undoHistory{}
+}
@@ -146,6 +146,34 @@ func (c *client) PrevCmd(upto int, prefix string) (storedefs.Cmd, error) {
 	return storedefs.Cmd{Text: res.Text, Seq: res.Seq}, err
 }
 
+func (c *client) SetCmdMeta(seq int, meta storedefs.CmdMeta) error {
+	req := &api.SetCmdMetaRequest{Seq: seq, Meta: meta}
+	res := &api.SetCmdMetaResponse{}
+	err := c.call("SetCmdMeta", req, res)
+	return err
+}
+
+func (c *client) CmdMeta(seq int) (storedefs.CmdMeta, error) {
+	req := &api.CmdMetaRequest{Seq: seq}
+	res := &api.CmdMetaResponse{}
+	err := c.call("CmdMeta", req, res)
+	return res.Meta, err
+}
+
+func (c *client) SearchCmds(query string, opts storedefs.SearchOpts) ([]storedefs.Cmd, error) {
+	req := &api.SearchCmdsRequest{Query: query, Opts: opts}
+	res := &api.SearchCmdsResponse{}
+	err := c.call("SearchCmds", req, res)
+	return res.Cmds, err
+}
+
+func (c *client) MergeCmds(cmds []storedefs.Cmd) (int, error) {
+	req := &api.MergeCmdsRequest{Cmds: cmds}
+	res := &api.MergeCmdsResponse{}
+	err := c.call("MergeCmds", req, res)
+	return res.Added, err
+}
+
 func (c *client) AddDir(dir string, incFactor float64) error {
 	req := &api.AddDirRequest{Dir: dir, IncFactor: incFactor}
 	res := &api.AddDirResponse{}
@@ -166,3 +194,10 @@ func (c *client) Dirs(blacklist map[string]struct{}) ([]storedefs.Dir, error) {
 	err := c.call("Dirs", req, res)
 	return res.Dirs, err
 }
+
+func (c *client) MergeDirs(dirs []storedefs.Dir) (int, error) {
+	req := &api.MergeDirsRequest{Dirs: dirs}
+	res := &api.MergeDirsResponse{}
+	err := c.call("MergeDirs", req, res)
+	return res.Changed, err
+}
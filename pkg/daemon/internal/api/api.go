@@ -7,7 +7,7 @@ import (
 )
 
 // Version is the API version. It should be bumped any time the API changes.
-const Version = -93
+const Version = -90
 
 // ServiceName is the name of the RPC service exposed by the daemon.
 const ServiceName = "Daemon"
@@ -94,6 +94,38 @@ type PrevCmdResponse struct {
 	Text string
 }
 
+type SetCmdMetaRequest struct {
+	Seq  int
+	Meta storedefs.CmdMeta
+}
+
+type SetCmdMetaResponse struct{}
+
+type CmdMetaRequest struct {
+	Seq int
+}
+
+type CmdMetaResponse struct {
+	Meta storedefs.CmdMeta
+}
+
+type SearchCmdsRequest struct {
+	Query string
+	Opts  storedefs.SearchOpts
+}
+
+type SearchCmdsResponse struct {
+	Cmds []storedefs.Cmd
+}
+
+type MergeCmdsRequest struct {
+	Cmds []storedefs.Cmd
+}
+
+type MergeCmdsResponse struct {
+	Added int
+}
+
 // Dir requests.
 
 type AddDirRequest struct {
@@ -116,3 +148,11 @@ type DirsRequest struct {
 type DirsResponse struct {
 	Dirs []storedefs.Dir
 }
+
+type MergeDirsRequest struct {
+	Dirs []storedefs.Dir
+}
+
+type MergeDirsResponse struct {
+	Changed int
+}
@@ -34,6 +34,8 @@ func TestProgram_ServesClientRequests(t *testing.T) {
 	// Test store requests.
 	storetest.TestCmd(t, client)
 	storetest.TestDir(t, client)
+	storetest.TestSearchCmds(t, client)
+	storetest.TestMerge(t, client)
 }
 
 func TestProgram_StillServesIfCannotOpenDB(t *testing.T) {
@@ -90,6 +90,40 @@ func (s *service) PrevCmd(req *api.PrevCmdRequest, res *api.PrevCmdResponse) err
 	return err
 }
 
+func (s *service) SetCmdMeta(req *api.SetCmdMetaRequest, res *api.SetCmdMetaResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.SetCmdMeta(req.Seq, req.Meta)
+}
+
+func (s *service) CmdMeta(req *api.CmdMetaRequest, res *api.CmdMetaResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	meta, err := s.store.CmdMeta(req.Seq)
+	res.Meta = meta
+	return err
+}
+
+func (s *service) SearchCmds(req *api.SearchCmdsRequest, res *api.SearchCmdsResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	cmds, err := s.store.SearchCmds(req.Query, req.Opts)
+	res.Cmds = cmds
+	return err
+}
+
+func (s *service) MergeCmds(req *api.MergeCmdsRequest, res *api.MergeCmdsResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	added, err := s.store.MergeCmds(req.Cmds)
+	res.Added = added
+	return err
+}
+
 func (s *service) AddDir(req *api.AddDirRequest, res *api.AddDirResponse) error {
 	if s.err != nil {
 		return s.err
@@ -112,3 +146,12 @@ func (s *service) Dirs(req *api.DirsRequest, res *api.DirsResponse) error {
 	res.Dirs = dirs
 	return err
 }
+
+func (s *service) MergeDirs(req *api.MergeDirsRequest, res *api.MergeDirsResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	changed, err := s.store.MergeDirs(req.Dirs)
+	res.Changed = changed
+	return err
+}
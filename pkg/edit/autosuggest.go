@@ -0,0 +1,229 @@
+package edit
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"src.elv.sh/pkg/cli"
+	"src.elv.sh/pkg/cli/histutil"
+	"src.elv.sh/pkg/cli/modes"
+	"src.elv.sh/pkg/cli/tk"
+	"src.elv.sh/pkg/eval"
+)
+
+func initAutosuggest(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, nb eval.NsBuilder, hs histutil.Store) {
+	suggestFromHistoryFn := eval.NewGoFn("suggest-from-history",
+		func(fm *eval.Frame, code string) error {
+			return suggestFromHistory(fm, hs, code)
+		})
+	autosuggestVar := newFnVar(suggestFromHistoryFn)
+	s := newAutosuggester(func(code string) string {
+		return callAutosuggest(ed, ev, autosuggestVar.Get().(eval.Callable), code)
+	})
+	appSpec.Autosuggester = s
+	// The history may have changed since the last time the code was read.
+	appSpec.BeforeReadline = append(appSpec.BeforeReadline, s.invalidate)
+
+	nb.AddVar("autosuggest", autosuggestVar)
+	nb.AddFn("suggest-from-history", suggestFromHistoryFn)
+	nb.AddGoFns(map[string]any{
+		"accept-suggestion": func() {
+			acceptSuggestion(ed.app, s, nil)
+		},
+		"accept-suggestion-word": func() {
+			acceptSuggestion(ed.app, s, moveDotPastWord)
+		},
+	})
+}
+
+const autosuggestLatesBufferSize = 1
+
+// Computes suggestions in the background, like the highlighter does for
+// checking commands, since the $edit:autosuggest callback can be slow. The
+// suggestion for the last code is cached, since rendering can happen many
+// times for the same code, and the suggestion shown must be the same as the
+// one accepted.
+type autosuggester struct {
+	compute func(code string) string
+	lates   chan struct{}
+
+	mu sync.Mutex
+	// The code that the cached suggestion is for, and the suggestion.
+	valid bool
+	code  string
+	rest  string
+	// Whether a goroutine is computing suggestions, and the code it should
+	// compute the suggestion for next.
+	computing bool
+	wanted    string
+	// Incremented when the cache is invalidated, so that suggestions computed
+	// before that are discarded.
+	generation int
+}
+
+func newAutosuggester(compute func(code string) string) *autosuggester {
+	return &autosuggester{compute: compute,
+		lates: make(chan struct{}, autosuggestLatesBufferSize)}
+}
+
+// Get returns the cached suggestion for code, starting to compute it if it
+// isn't cached.
+func (s *autosuggester) Get(code string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.valid && s.code == code {
+		return s.rest
+	}
+	s.wanted = code
+	if !s.computing {
+		s.computing = true
+		go s.run()
+	}
+	return s.shownLocked(code)
+}
+
+// LateUpdates returns a channel for notifying late updates.
+func (s *autosuggester) LateUpdates() <-chan struct{} {
+	return s.lates
+}
+
+// Computes suggestions until the suggestion for the latest wanted code is
+// cached. The mutex is not held when computing, since that may call Elvish
+// code that causes a redraw.
+func (s *autosuggester) run() {
+	s.mu.Lock()
+	for {
+		code, generation := s.wanted, s.generation
+		s.mu.Unlock()
+		rest := s.compute(code)
+		s.mu.Lock()
+		if generation == s.generation {
+			s.valid, s.code, s.rest = true, code, rest
+			if code == s.wanted {
+				break
+			}
+		}
+	}
+	s.computing = false
+	s.mu.Unlock()
+	select {
+	case s.lates <- struct{}{}:
+	default:
+		// A late update is already pending.
+	}
+}
+
+// Returns the suggestion that Get returns for code, without computing it.
+func (s *autosuggester) shown(code string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shownLocked(code)
+}
+
+// Like shown, but must be called with the mutex held.
+func (s *autosuggester) shownLocked(code string) string {
+	if !s.valid || !strings.HasPrefix(code, s.code) {
+		return ""
+	}
+	// While the suggestion for code is being computed, the suggestion for the
+	// previous code is still usable if code is a prefix of the suggested code.
+	if rest, ok := strings.CutPrefix(s.code+s.rest, code); ok {
+		return rest
+	}
+	return ""
+}
+
+func (s *autosuggester) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.valid = false
+	s.generation++
+}
+
+// Calls the $edit:autosuggest callback, and returns the part of its first
+// string output after code. Outputs that don't start with code are ignored.
+func callAutosuggest(nt notifier, ev *eval.Evaler, fn eval.Callable, code string) string {
+	port1, collect, err := eval.ValueCapturePort()
+	if err != nil {
+		nt.notifyf("cannot create pipe for autosuggest: %v", err)
+		return ""
+	}
+	port2, done2 := makeNotifyPort(nt)
+	err = ev.Call(fn,
+		eval.CallCfg{Args: []any{code}, From: "[autosuggest]"},
+		eval.EvalCfg{Ports: []*eval.Port{nil, port1, port2}})
+	outs := collect()
+	done2()
+	if err != nil {
+		nt.notifyError("autosuggest", err)
+		return ""
+	}
+	for _, out := range outs {
+		if s, ok := out.(string); ok && len(s) > len(code) && strings.HasPrefix(s, code) {
+			return s[len(code):]
+		}
+	}
+	return ""
+}
+
+// Outputs the most recent command in the history that starts with code and is
+// not the same as code.
+func suggestFromHistory(fm *eval.Frame, hs histutil.Store, code string) error {
+	if code == "" {
+		return nil
+	}
+	c := hs.Cursor(code)
+	for {
+		c.Prev()
+		cmd, err := c.Get()
+		if err == histutil.ErrEndOfHistory {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if cmd.Text != code {
+			return fm.ValueOutput().Put(cmd.Text)
+		}
+	}
+}
+
+// Inserts the suggestion shown in the main code area, up to where the mover
+// moves the dot to, or all of it if the mover is nil. Does nothing if there is
+// no suggestion.
+func acceptSuggestion(app cli.App, s *autosuggester, m pureMover) {
+	codeArea, err := modes.FocusedCodeArea(app)
+	// Code areas in addons, like the one of the minibuffer, don't show
+	// suggestions.
+	if err != nil || slices.Contains(app.CopyState().Addons, tk.Widget(codeArea)) {
+		// This function is bound to keys like Right by default, so it should
+		// be usable as a no-op when there is no suggestion.
+		return
+	}
+	state := codeArea.CopyState()
+	buf := state.Buffer
+	if state.HideSuggestion || state.Pending != (tk.PendingCode{}) ||
+		buf.Content == "" || buf.Dot != len(buf.Content) {
+		return
+	}
+	rest := s.shown(buf.Content)
+	if rest == "" {
+		return
+	}
+	if m != nil {
+		rest = rest[:m(buf.Content+rest, buf.Dot)-buf.Dot]
+	}
+	codeArea.MutateState(func(st *tk.CodeAreaState) {
+		if st.Buffer == buf {
+			st.Buffer.InsertAtDot(rest)
+		}
+	})
+}
+
+// Moves the dot to the end of the first word to its right. Unlike
+// moveDotRightWord, this leaves the whitespace after the word alone, so that
+// accepting a suggestion word by word doesn't leave trailing whitespace.
+func moveDotPastWord(buffer string, dot int) int {
+	pos := skipWsRight(categorizeWord, buffer, dot)
+	return skipSameCatRight(categorizeWord, buffer, pos)
+}
@@ -0,0 +1,154 @@
+package edit
+
+import (
+	"testing"
+
+	"src.elv.sh/pkg/cli/term"
+	"src.elv.sh/pkg/store/storedefs"
+	"src.elv.sh/pkg/ui"
+)
+
+func TestAutosuggest_FromHistory(t *testing.T) {
+	f := startAutosuggestTest(t)
+
+	f.TestTTY(t,
+		"~> echo", Styles,
+		"   vvvv", term.DotHere,
+		" foo bar", Styles,
+		"dddddddd")
+}
+
+func TestAutosuggest_SkipsExactMatch(t *testing.T) {
+	f := setup(t, storeOp(func(s storedefs.Store) {
+		s.AddCmd("echo foo")
+		s.AddCmd("echo")
+	}))
+
+	feedInput(f.TTYCtrl, "echo")
+	f.TestTTY(t,
+		"~> echo", Styles,
+		"   vvvv", term.DotHere,
+		" foo", Styles,
+		"dddd")
+}
+
+func TestAutosuggest_NotShownWhenDotIsNotAtEnd(t *testing.T) {
+	f := startAutosuggestTest(t)
+
+	f.TTYCtrl.Inject(term.K(ui.Left))
+	f.TestTTY(t,
+		"~> ech", Styles,
+		"   vvv", term.DotHere,
+		"o", Styles,
+		"v")
+}
+
+func TestAcceptSuggestion(t *testing.T) {
+	f := startAutosuggestTest(t)
+
+	f.TTYCtrl.Inject(term.K(ui.Right))
+	f.TestTTY(t,
+		"~> echo foo bar", Styles,
+		"   vvvv        ", term.DotHere)
+}
+
+func TestAcceptSuggestionWord(t *testing.T) {
+	f := startAutosuggestTest(t)
+
+	f.TTYCtrl.Inject(term.K('f', ui.Alt))
+	f.TestTTY(t,
+		"~> echo foo", Styles,
+		"   vvvv    ", term.DotHere,
+		" bar", Styles,
+		"dddd")
+}
+
+func TestAcceptSuggestion_NotInMinibuf(t *testing.T) {
+	f := startAutosuggestTest(t)
+
+	evals(f.Evaler, `edit:minibuf:start`)
+	feedInput(f.TTYCtrl, "echo")
+	evals(f.Evaler, `edit:accept-suggestion`)
+	f.TTYCtrl.Inject(term.K(ui.Right), term.K(ui.End))
+	f.TestTTY(t,
+		"~> echo foo bar", Styles,
+		"   vvvvdddddddd", "\n",
+		" MINIBUF  echo", Styles,
+		"*********     ", term.DotHere,
+	)
+}
+
+func TestAutosuggest_Slow(t *testing.T) {
+	f := setup(t, rc(`
+		var ch = (make-chan)
+		# Waits until $ch is closed.
+		set edit:autosuggest = {|code| each {|_| } $ch; put $code' suggested' }`))
+
+	// The code is shown before the suggestion is computed.
+	feedInput(f.TTYCtrl, "echo")
+	f.TestTTY(t,
+		"~> echo", Styles,
+		"   vvvv", term.DotHere)
+	// Nothing is accepted before the suggestion is shown.
+	f.TTYCtrl.Inject(term.K(ui.Right))
+
+	evals(f.Evaler, `close-chan $ch`)
+	f.TestTTY(t,
+		"~> echo", Styles,
+		"   vvvv", term.DotHere,
+		" suggested", Styles,
+		"dddddddddd")
+}
+
+func TestAutosuggest_Custom(t *testing.T) {
+	f := setup(t, rc(`set edit:autosuggest = {|code| put foo $code' suggested' }`))
+
+	feedInput(f.TTYCtrl, "echo")
+	f.TestTTY(t,
+		"~> echo", Styles,
+		"   vvvv", term.DotHere,
+		" suggested", Styles,
+		"dddddddddd")
+
+	evals(f.Evaler, `edit:accept-suggestion`)
+	if code := codeArea(f.Editor.app).CopyState().Buffer.Content; code != "echo suggested" {
+		t.Errorf("code = %q, want %q", code, "echo suggested")
+	}
+}
+
+func TestAutosuggest_Disabled(t *testing.T) {
+	f := setup(t, rc(`set edit:autosuggest = {|_| }`),
+		storeOp(func(s storedefs.Store) { s.AddCmd("echo foo bar") }))
+
+	feedInput(f.TTYCtrl, "echo")
+	f.TestTTY(t,
+		"~> echo", Styles,
+		"   vvvv", term.DotHere)
+}
+
+func TestAutosuggest_Error(t *testing.T) {
+	f := setup(t, rc(`set edit:autosuggest = {|_| fail bad }`))
+
+	// Use a single key so that only one suggestion is computed and fails.
+	feedInput(f.TTYCtrl, "e")
+	f.TestTTY(t,
+		"~> e", Styles,
+		"   !", term.DotHere)
+	f.TTYCtrl.TestMsg(t, ui.T(
+		"[autosuggest error] bad\n"+
+			`see stack trace with "show $edit:exceptions[0]"`))
+}
+
+func startAutosuggestTest(t *testing.T) *fixture {
+	f := setup(t, storeOp(func(s storedefs.Store) {
+		s.AddCmd("echo foo bar")
+	}))
+	feedInput(f.TTYCtrl, "echo")
+	// Suggestions are computed asynchronously; wait until it's shown.
+	f.TestTTY(t,
+		"~> echo", Styles,
+		"   vvvv", term.DotHere,
+		" foo bar", Styles,
+		"dddddddd")
+	return f
+}
@@ -1,6 +1,7 @@
 package edit
 
 import (
+	"errors"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -8,15 +9,14 @@ import (
 	"src.elv.sh/pkg/cli"
 	"src.elv.sh/pkg/cli/tk"
 	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/parse/parseutil"
 	"src.elv.sh/pkg/strutil"
 	"src.elv.sh/pkg/wcwidth"
 )
 
 func initBufferBuiltins(app cli.App, nb eval.NsBuilder) {
 	m := make(map[string]any)
-	for name, fn := range bufferBuiltinsData {
-		// Make a lexically scoped copy of fn.
-		fn := fn
+	addBufferBuiltin := func(name string, fn func(*tk.CodeBuffer)) {
 		m[name] = func() {
 			codeArea, ok := focusedCodeArea(app)
 			if !ok {
@@ -27,6 +27,52 @@ func initBufferBuiltins(app cli.App, nb eval.NsBuilder) {
 			})
 		}
 	}
+	for name, fn := range bufferBuiltinsData {
+		addBufferBuiltin(name, fn)
+	}
+	kr := &killRing{}
+	for name, mover := range killBuiltinsData {
+		addBufferBuiltin(name, makeKillToRing(kr, mover))
+	}
+	addBufferBuiltin("yank", kr.yank)
+	addBufferBuiltin("yank-pop", kr.yankPop)
+	m["find-rune"] = func(fm *eval.Frame, opts findRuneOpts, s string) error {
+		codeArea, ok := focusedCodeArea(app)
+		if !ok {
+			return nil
+		}
+		buf := codeArea.CopyState().Buffer
+		if pos, ok := findRune(buf.Content, buf.Dot, s, opts); ok {
+			return fm.ValueOutput().Put(pos)
+		}
+		return nil
+	}
+	m["text-object"] = func(fm *eval.Frame, name string) error {
+		codeArea, ok := focusedCodeArea(app)
+		if !ok {
+			return nil
+		}
+		buf := codeArea.CopyState().Buffer
+		from, to, ok, err := textObject(buf.Content, buf.Dot, name)
+		if err != nil || !ok {
+			return err
+		}
+		out := fm.ValueOutput()
+		if err := out.Put(from); err != nil {
+			return err
+		}
+		return out.Put(to)
+	}
+	m["undo"] = func() {
+		if codeArea, ok := focusedCodeArea(app); ok {
+			codeArea.Undo()
+		}
+	}
+	m["redo"] = func() {
+		if codeArea, ok := focusedCodeArea(app); ok {
+			codeArea.Redo()
+		}
+	}
 	nb.AddGoFns(m)
 }
 
@@ -42,19 +88,20 @@ var bufferBuiltinsData = map[string]func(*tk.CodeBuffer){
 	"move-dot-sol":              makeMove(moveDotSOL),
 	"move-dot-eol":              makeMove(moveDotEOL),
 
+	"move-dot-sol-non-blank":        makeMove(moveDotSOLNonBlank),
+	"move-dot-right-word-end":       makeMove(moveDotRightWordEnd),
+	"move-dot-right-small-word-end": makeMove(moveDotRightSmallWordEnd),
+	"move-dot-right-alnum-word-end": makeMove(moveDotRightAlnumWordEnd),
+
 	"move-dot-up":   makeMove(moveDotUp),
 	"move-dot-down": makeMove(moveDotDown),
 
-	"kill-rune-left":        makeKill(moveDotLeft),
-	"kill-rune-right":       makeKill(moveDotRight),
-	"kill-word-left":        makeKill(moveDotLeftWord),
-	"kill-word-right":       makeKill(moveDotRightWord),
-	"kill-small-word-left":  makeKill(moveDotLeftSmallWord),
-	"kill-small-word-right": makeKill(moveDotRightSmallWord),
-	"kill-alnum-word-left":  makeKill(moveDotLeftAlnumWord),
-	"kill-alnum-word-right": makeKill(moveDotRightAlnumWord),
-	"kill-line-left":        makeKill(moveDotSOL),
-	"kill-line-right":       makeKill(moveDotEOL),
+	"move-dot-matching-bracket": makeMove(moveDotMatchingBracket),
+	"move-dot-block-start":      makeMove(moveDotBlockStart),
+	"move-dot-block-end":        makeMove(moveDotBlockEnd),
+
+	"kill-rune-left":  makeKill(moveDotLeft),
+	"kill-rune-right": makeKill(moveDotRight),
 
 	"transpose-rune":       makeTransform(transposeRunes),
 	"transpose-word":       makeTransform(transposeWord),
@@ -62,6 +109,19 @@ var bufferBuiltinsData = map[string]func(*tk.CodeBuffer){
 	"transpose-alnum-word": makeTransform(transposeAlnumWord),
 }
 
+// Kill builtins that save the killed text in the kill ring. Like in readline,
+// killing single runes doesn't use the kill ring.
+var killBuiltinsData = map[string]pureMover{
+	"kill-word-left":        moveDotLeftWord,
+	"kill-word-right":       moveDotRightWord,
+	"kill-small-word-left":  moveDotLeftSmallWord,
+	"kill-small-word-right": moveDotRightSmallWord,
+	"kill-alnum-word-left":  moveDotLeftAlnumWord,
+	"kill-alnum-word-right": moveDotRightAlnumWord,
+	"kill-line-left":        moveDotSOL,
+	"kill-line-right":       moveDotEOL,
+}
+
 // A pure function that takes the current buffer and dot, and returns a new
 // value for the dot. Used to derive move- and kill- functions that operate on
 // the editor state.
@@ -118,6 +178,11 @@ func moveDotEOL(buffer string, dot int) int {
 	return strutil.FindFirstEOL(buffer[dot:]) + dot
 }
 
+func moveDotSOLNonBlank(buffer string, dot int) int {
+	sol := strutil.FindLastSOL(buffer[:dot])
+	return sol + len(buffer[sol:]) - len(strings.TrimLeft(buffer[sol:], " \t"))
+}
+
 func moveDotUp(buffer string, dot int) int {
 	sol := strutil.FindLastSOL(buffer[:dot])
 	if sol == 0 {
@@ -186,6 +251,10 @@ func moveDotRightWord(buffer string, dot int) int {
 	return moveDotRightGeneralWord(categorizeWord, buffer, dot)
 }
 
+func moveDotRightWordEnd(buffer string, dot int) int {
+	return moveDotRightGeneralWordEnd(categorizeWord, buffer, dot)
+}
+
 func transposeWord(buffer string, dot int) (string, int) {
 	return transposeGeneralWord(categorizeWord, buffer, dot)
 }
@@ -207,6 +276,10 @@ func moveDotRightSmallWord(buffer string, dot int) int {
 	return moveDotRightGeneralWord(tk.CategorizeSmallWord, buffer, dot)
 }
 
+func moveDotRightSmallWordEnd(buffer string, dot int) int {
+	return moveDotRightGeneralWordEnd(tk.CategorizeSmallWord, buffer, dot)
+}
+
 func transposeSmallWord(buffer string, dot int) (string, int) {
 	return transposeGeneralWord(tk.CategorizeSmallWord, buffer, dot)
 }
@@ -219,6 +292,10 @@ func moveDotRightAlnumWord(buffer string, dot int) int {
 	return moveDotRightGeneralWord(categorizeAlnum, buffer, dot)
 }
 
+func moveDotRightAlnumWordEnd(buffer string, dot int) int {
+	return moveDotRightGeneralWordEnd(categorizeAlnum, buffer, dot)
+}
+
 func transposeAlnumWord(buffer string, dot int) (string, int) {
 	return transposeGeneralWord(categorizeAlnum, buffer, dot)
 }
@@ -322,6 +399,21 @@ func moveDotRightGeneralWord(categorize categorizer, buffer string, dot int) int
 	return pos
 }
 
+// Move the dot onto the last rune of the first word that ends after the rune at
+// the dot, using the word flavor described by the categorizer. This is how the
+// dot moves in vi, where it is on a rune rather than between two runes.
+func moveDotRightGeneralWordEnd(categorize categorizer, buffer string, dot int) int {
+	// skip the rune at the dot, and whitespaces after it
+	pos := skipWsRight(categorize, buffer, moveDotRight(buffer, dot))
+	if pos == len(buffer) {
+		// There is no word after the dot.
+		return dot
+	}
+	// skip the word, and move back onto its last rune
+	pos = skipSameCatRight(categorize, buffer, pos)
+	return moveDotLeft(buffer, pos)
+}
+
 // Transposes the words around the cursor, using the word flavor described
 // by the categorizer.
 func transposeGeneralWord(categorize categorizer, buffer string, dot int) (string, int) {
@@ -414,3 +506,210 @@ func skipCatRight(categorize categorizer, cat int, buffer string, pos int) int {
 
 	return len(buffer) - len(right)
 }
+
+func moveDotMatchingBracket(buffer string, dot int) int {
+	brackets := parseutil.Brackets(buffer)
+	for _, b := range brackets {
+		switch {
+		case b.Close == -1:
+			continue
+		case b.Open <= dot && dot < b.Body:
+			return b.Close
+		case dot == b.Close:
+			return b.Open
+		}
+	}
+	// Also support jumping from just after a closing bracket, which is where
+	// the dot is after typing it.
+	for _, b := range brackets {
+		if b.Close != -1 && dot-1 == b.Close {
+			return b.Open
+		}
+	}
+	return dot
+}
+
+func moveDotBlockStart(buffer string, dot int) int {
+	b, ok := parseutil.EnclosingBracket(parseutil.Brackets(buffer), dot)
+	switch {
+	case !ok:
+		return dot
+	case dot == b.Body:
+		// Already at the start of the block; move out of it.
+		return b.Open
+	default:
+		return b.Body
+	}
+}
+
+func moveDotBlockEnd(buffer string, dot int) int {
+	b, ok := parseutil.EnclosingBracket(parseutil.Brackets(buffer), dot)
+	switch {
+	case !ok:
+		return dot
+	case b.Close == -1:
+		return len(buffer)
+	case dot == b.Close:
+		// Already at the end of the block; move out of it.
+		return b.Close + 1
+	default:
+		return b.Close
+	}
+}
+
+type findRuneOpts struct {
+	Count    int
+	Backward bool
+	Till     bool
+}
+
+func (o *findRuneOpts) SetDefaultOptions() { o.Count = 1 }
+
+// Finds the position of the opts.Count-th occurrence of s after the rune at the
+// dot, or before the dot if opts.Backward is true, in the line of the dot. If
+// opts.Till is true, the position is moved one rune towards the dot.
+func findRune(buffer string, dot int, s string, opts findRuneOpts) (int, bool) {
+	if s == "" || opts.Count < 1 {
+		return 0, false
+	}
+	if opts.Backward {
+		sol := strutil.FindLastSOL(buffer[:dot])
+		pos := dot
+		for i := 0; i < opts.Count; i++ {
+			j := strings.LastIndex(buffer[sol:pos], s)
+			if j == -1 {
+				return 0, false
+			}
+			pos = sol + j
+		}
+		if opts.Till {
+			pos += len(s)
+		}
+		return pos, true
+	}
+	eol := strutil.FindFirstEOL(buffer[dot:]) + dot
+	pos := moveDotRight(buffer, dot)
+	if pos > eol {
+		return 0, false
+	}
+	for i := 0; i < opts.Count; i++ {
+		if i > 0 {
+			pos += len(s)
+		}
+		j := strings.Index(buffer[pos:eol], s)
+		if j == -1 {
+			return 0, false
+		}
+		pos += j
+	}
+	if opts.Till {
+		pos = moveDotLeft(buffer, pos)
+	}
+	return pos, true
+}
+
+var errBadTextObject = errors.New("bad text object")
+
+// Returns the range of the text object around the dot. The name of the text
+// object follows vi: "i" or "a" for the inner text or all of the object,
+// followed by the type of the object.
+func textObject(buffer string, dot int, name string) (from, to int, ok bool, err error) {
+	if len(name) != 2 || (name[0] != 'i' && name[0] != 'a') {
+		return 0, 0, false, errBadTextObject
+	}
+	around := name[0] == 'a'
+	switch name[1] {
+	case 'w':
+		from, to, ok = wordObject(tk.CategorizeSmallWord, buffer, dot, around)
+	case 'W':
+		from, to, ok = wordObject(categorizeWord, buffer, dot, around)
+	case '"', '\'', '`':
+		from, to, ok = quoteObject(buffer, dot, name[1], around)
+	case '(', ')', 'b':
+		from, to, ok = bracketObject(buffer, dot, '(', around)
+	case '[', ']':
+		from, to, ok = bracketObject(buffer, dot, '[', around)
+	case '{', '}', 'B':
+		from, to, ok = bracketObject(buffer, dot, '{', around)
+	default:
+		return 0, 0, false, errBadTextObject
+	}
+	return from, to, ok, nil
+}
+
+// Returns the range of the word or run of whitespaces that the rune at the dot
+// is in. If around is true, also includes the whitespaces after the word, or
+// before the word if there are none after it; or the word after the
+// whitespaces.
+func wordObject(categorize categorizer, buffer string, dot int, around bool) (int, int, bool) {
+	if dot == len(buffer) {
+		if dot == 0 {
+			return 0, 0, false
+		}
+		dot = moveDotLeft(buffer, dot)
+	}
+	r, _ := utf8.DecodeRuneInString(buffer[dot:])
+	cat := categorize(r)
+	from := skipCatLeft(categorize, cat, buffer, dot)
+	to := skipCatRight(categorize, cat, buffer, dot)
+	switch {
+	case !around:
+	case cat == 0:
+		to = skipSameCatRight(categorize, buffer, to)
+	case skipWsRight(categorize, buffer, to) > to:
+		to = skipWsRight(categorize, buffer, to)
+	default:
+		from = skipWsLeft(categorize, buffer, from)
+	}
+	return from, to, true
+}
+
+// Returns the range of the text between the first pair of quotes in the line
+// of the dot that ends at or after the dot. If around is true, also includes
+// the quotes, and the spaces after them, or before them if there are none
+// after them.
+func quoteObject(buffer string, dot int, quote byte, around bool) (int, int, bool) {
+	sol := strutil.FindLastSOL(buffer[:dot])
+	eol := strutil.FindFirstEOL(buffer[dot:]) + dot
+	var quotes []int
+	for i := sol; i < eol; i++ {
+		if buffer[i] == '\\' && quote != '\'' {
+			// Skip the escaped byte.
+			i++
+		} else if buffer[i] == quote {
+			quotes = append(quotes, i)
+		}
+	}
+	for i := 0; i+1 < len(quotes); i += 2 {
+		open, close := quotes[i], quotes[i+1]
+		if dot > close {
+			continue
+		}
+		if !around {
+			return open + 1, close, true
+		}
+		to := close + 1 + len(buffer[close+1:eol]) - len(strings.TrimLeft(buffer[close+1:eol], " \t"))
+		if to == close+1 {
+			return len(strings.TrimRight(buffer[sol:open], " \t")) + sol, to, true
+		}
+		return open, to, true
+	}
+	return 0, 0, false
+}
+
+// Returns the range of the text in the innermost pair of the given type of
+// brackets that encloses the dot. If around is true, also includes the
+// brackets. Brackets in string literals and comments are ignored.
+func bracketObject(buffer string, dot int, open byte, around bool) (int, int, bool) {
+	brackets := parseutil.Brackets(buffer)
+	for i := len(brackets) - 1; i >= 0; i-- {
+		b := brackets[i]
+		if buffer[b.Body-1] == open && b.Close != -1 && b.Open <= dot && dot <= b.Close {
+			if around {
+				return b.Open, b.Close + 1, true
+			}
+			return b.Body, b.Close, true
+		}
+	}
+	return 0, 0, false
+}
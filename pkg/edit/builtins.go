@@ -129,7 +129,7 @@ func smartEnter(ed *Editor) {
 	codeArea.MutateState(func(s *tk.CodeAreaState) {
 		buf := &s.Buffer
 		if !isSyntaxComplete(buf.Content) {
-			buf.InsertAtDot("\n")
+			buf.InsertNewline(ed.indent())
 			insertedNewline = true
 		}
 	})
@@ -145,6 +145,16 @@ func smartEnter(ed *Editor) {
 	ed.app.CommitCode()
 }
 
+func insertNewline(ed *Editor) {
+	codeArea, ok := focusedCodeArea(ed.app)
+	if !ok {
+		return
+	}
+	codeArea.MutateState(func(s *tk.CodeAreaState) {
+		s.Buffer.InsertNewline(ed.indent())
+	})
+}
+
 func isSyntaxComplete(code string) bool {
 	_, err := parse.Parse(parse.Source{Name: "[syntax check]", Code: code}, parse.Config{})
 	for _, e := range parse.UnpackErrors(err) {
@@ -168,9 +178,10 @@ func wordify(fm *eval.Frame, code string) error {
 
 func initTTYBuiltins(app cli.App, tty cli.TTY, nb eval.NsBuilder) {
 	nb.AddGoFns(map[string]any{
-		"insert-raw":       func() { insertRaw(app, tty) },
-		"-insert-key-name": func() { insertKeyName(app) },
-		"clear":            func() { clear(app, tty) },
+		"insert-raw":              func() { insertRaw(app, tty) },
+		"-insert-key-name":        func() { insertKeyName(app) },
+		"clear":                   func() { clear(app, tty) },
+		"edit-in-external-editor": func() error { return editInExternalEditor(app) },
 	})
 }
 
@@ -185,6 +196,7 @@ func initMiscBuiltins(ed *Editor, nb eval.NsBuilder) {
 		"return-line":    ed.app.CommitCode,
 		"return-eof":     ed.app.CommitEOF,
 		"smart-enter":    func() { smartEnter(ed) },
+		"insert-newline": func() { insertNewline(ed) },
 		"wordify":        wordify,
 	})
 }
@@ -1,13 +1,18 @@
 package edit
 
 import (
+	"errors"
 	"io"
+	"os"
 	"strings"
 	"testing"
 
 	"src.elv.sh/pkg/cli/modes"
 	"src.elv.sh/pkg/cli/term"
 	"src.elv.sh/pkg/cli/tk"
+	"src.elv.sh/pkg/env"
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/testutil"
 	"src.elv.sh/pkg/tt"
 	"src.elv.sh/pkg/ui"
 )
@@ -108,6 +113,52 @@ func TestClear(t *testing.T) {
 	}
 }
 
+func TestEditInExternalEditor(t *testing.T) {
+	testutil.Setenv(t, env.EDITOR, "my-editor --wait")
+	testutil.Set(t, &runEditor, func(editor, path string) error {
+		if editor != "my-editor --wait" {
+			t.Errorf("got editor %q, want %q", editor, "my-editor --wait")
+		}
+		code := must.ReadFileString(path)
+		return os.WriteFile(path, []byte(code+" |\n  slurp\n"), 0o600)
+	})
+	f := setup(t)
+
+	f.SetCodeBuffer(tk.CodeBuffer{Content: "echo foo", Dot: 0})
+	f.TTYCtrl.Inject(term.K('e', ui.Alt))
+	f.TestTTY(t,
+		"~> echo foo |", Styles,
+		"   vvvv     v", "\n",
+		"     slurp", Styles,
+		"     vvvvv", term.DotHere)
+}
+
+func TestEditInExternalEditor_EditorError(t *testing.T) {
+	testutil.Setenv(t, env.EDITOR, "my-editor")
+	testutil.Set(t, &runEditor, func(editor, path string) error {
+		os.WriteFile(path, []byte("echo bar"), 0o600)
+		return errors.New("editor error")
+	})
+	f := setup(t)
+
+	f.SetCodeBuffer(tk.CodeBuffer{Content: "echo foo", Dot: 8})
+	f.TTYCtrl.Inject(term.K('e', ui.Alt))
+	f.TTYCtrl.TestMsg(t, ui.T("[binding error] editor error"))
+	// The code is left unchanged.
+	wantBuf := tk.CodeBuffer{Content: "echo foo", Dot: 8}
+	if buf := codeArea(f.Editor.app).CopyState().Buffer; buf != wantBuf {
+		t.Errorf("got buf %v, want %v", buf, wantBuf)
+	}
+}
+
+func TestEditInExternalEditor_NoEditor(t *testing.T) {
+	testutil.Setenv(t, env.EDITOR, "")
+	f := setup(t)
+
+	f.TTYCtrl.Inject(term.K('e', ui.Alt))
+	f.TTYCtrl.TestMsg(t, ui.T("[binding error] "+errNoEditor.Error()))
+}
+
 func TestNotify(t *testing.T) {
 	f := setup(t)
 	evals(f.Evaler, "edit:notify string")
@@ -151,6 +202,17 @@ func TestReturnEOF(t *testing.T) {
 func TestSmartEnter_InsertsNewlineWhenIncomplete(t *testing.T) {
 	f := setup(t)
 
+	f.SetCodeBuffer(tk.CodeBuffer{Content: "put [", Dot: 5})
+	evals(f.Evaler, `edit:smart-enter`)
+	wantBuf := tk.CodeBuffer{Content: "put [\n  ", Dot: 8}
+	if buf := codeArea(f.Editor.app).CopyState().Buffer; buf != wantBuf {
+		t.Errorf("got code buffer %v, want %v", buf, wantBuf)
+	}
+}
+
+func TestSmartEnter_IndentCanBeCustomized(t *testing.T) {
+	f := setup(t, rc(`set edit:insert:indent = ''`))
+
 	f.SetCodeBuffer(tk.CodeBuffer{Content: "put [", Dot: 5})
 	evals(f.Evaler, `edit:smart-enter`)
 	wantBuf := tk.CodeBuffer{Content: "put [\n", Dot: 6}
@@ -159,6 +221,42 @@ func TestSmartEnter_InsertsNewlineWhenIncomplete(t *testing.T) {
 	}
 }
 
+func TestInsertNewline(t *testing.T) {
+	f := setup(t)
+
+	f.SetCodeBuffer(tk.CodeBuffer{Content: "f {}", Dot: 3})
+	evals(f.Evaler, `edit:insert-newline`)
+	wantBuf := tk.CodeBuffer{Content: "f {\n  \n}", Dot: 6}
+	if buf := codeArea(f.Editor.app).CopyState().Buffer; buf != wantBuf {
+		t.Errorf("got code buffer %v, want %v", buf, wantBuf)
+	}
+}
+
+func TestInsert_ClosingBracketIsDedented(t *testing.T) {
+	f := setup(t)
+
+	f.SetCodeBuffer(tk.CodeBuffer{Content: "f {\n  put a\n  ", Dot: 14})
+	f.TTYCtrl.Inject(term.K('}'))
+	f.TestTTY(t,
+		"~> f {", Styles,
+		"   ! b", "\n",
+		"     put a", Styles,
+		"     vvv  ", "\n",
+		"   }", Styles,
+		"   b", term.DotHere)
+}
+
+func TestInsert_AutoPair(t *testing.T) {
+	f := setup(t, rc(`set edit:insert:auto-pair = $true`))
+
+	feedInput(f.TTYCtrl, "put [a")
+	f.TestTTY(t,
+		"~> put [a", Styles,
+		"   vvv b ", term.DotHere,
+		"]", Styles,
+		"b")
+}
+
 func TestSmartEnter_AcceptsCodeWhenWholeBufferIsComplete(t *testing.T) {
 	f := setup(t)
 
@@ -197,6 +295,16 @@ var bufferBuiltinsTests = []struct {
 		tk.CodeBuffer{Content: "ab", Dot: 1},
 		tk.CodeBuffer{Content: "a", Dot: 1},
 	},
+	{
+		"kill-word-left",
+		tk.CodeBuffer{Content: "echo foo", Dot: 8},
+		tk.CodeBuffer{Content: "echo ", Dot: 5},
+	},
+	{
+		"kill-line-right",
+		tk.CodeBuffer{Content: "echo foo", Dot: 4},
+		tk.CodeBuffer{Content: "echo", Dot: 4},
+	},
 	{
 		"transpose-rune with empty buffer",
 		tk.CodeBuffer{Content: "", Dot: 0},
@@ -292,6 +400,57 @@ func TestBufferBuiltins(t *testing.T) {
 	}
 }
 
+func TestKillRing(t *testing.T) {
+	f := setup(t)
+	app := f.Editor.app
+	testBuffer := func(code string, wantBuf tk.CodeBuffer) {
+		t.Helper()
+		evals(f.Evaler, code)
+		if buf := codeArea(app).CopyState().Buffer; buf != wantBuf {
+			t.Errorf("after %s, got buf %v, want %v", code, buf, wantBuf)
+		}
+	}
+
+	f.SetCodeBuffer(tk.CodeBuffer{Content: "echo foo bar", Dot: 12})
+	// Consecutive kills add to the same entry.
+	testBuffer("edit:kill-word-left; edit:kill-word-left", tk.CodeBuffer{Content: "echo ", Dot: 5})
+	// Yanking inserts the last entry.
+	testBuffer("edit:yank", tk.CodeBuffer{Content: "echo foo bar", Dot: 12})
+	// Killing after a yank creates a new entry.
+	testBuffer("edit:move-dot-sol; edit:kill-word-right", tk.CodeBuffer{Content: "foo bar", Dot: 0})
+	testBuffer("edit:move-dot-eol; edit:yank", tk.CodeBuffer{Content: "foo barecho ", Dot: 12})
+	// Yank-pop replaces the yanked text with the entry before it, rotating to
+	// the last entry.
+	testBuffer("edit:yank-pop", tk.CodeBuffer{Content: "foo barfoo bar", Dot: 14})
+	testBuffer("edit:yank-pop", tk.CodeBuffer{Content: "foo barecho ", Dot: 12})
+	// Yank-pop does nothing if not right after a yank.
+	testBuffer("edit:move-dot-left; edit:yank-pop", tk.CodeBuffer{Content: "foo barecho ", Dot: 11})
+}
+
+func TestUndoRedo(t *testing.T) {
+	f := setup(t)
+	app := f.Editor.app
+	testBuffer := func(code string, wantBuf tk.CodeBuffer) {
+		t.Helper()
+		evals(f.Evaler, code)
+		if buf := codeArea(app).CopyState().Buffer; buf != wantBuf {
+			t.Errorf("after %s, got buf %v, want %v", code, buf, wantBuf)
+		}
+	}
+
+	feedInput(f.TTYCtrl, "echo foo")
+	f.TestTTY(t, "~> echo foo", Styles,
+		"   vvvv    ", term.DotHere)
+	testBuffer("edit:kill-word-left", tk.CodeBuffer{Content: "echo ", Dot: 5})
+	testBuffer("edit:undo", tk.CodeBuffer{Content: "echo foo", Dot: 8})
+	// Typing is undone in one step.
+	testBuffer("edit:undo", tk.CodeBuffer{Content: "", Dot: 0})
+	testBuffer("edit:redo", tk.CodeBuffer{Content: "echo foo", Dot: 8})
+	testBuffer("edit:redo", tk.CodeBuffer{Content: "echo ", Dot: 5})
+	// Nothing more to redo.
+	testBuffer("edit:redo", tk.CodeBuffer{Content: "echo ", Dot: 5})
+}
+
 // Builtins that expect the focused widget to be code areas. This
 // includes some builtins defined in files other than builtins.go.
 var focusedWidgetNotCodeAreaTests = []string{
@@ -361,6 +520,132 @@ func TestMoveDotSOLEOL(t *testing.T) {
 	)
 }
 
+func TestMoveDotMatchingBracket(t *testing.T) {
+	buffer := "f { [a] ?(b) }"
+	// Index:   01234567890123
+	tt.Test(t, moveDotMatchingBracket,
+		Args(buffer, 2).Rets(13),
+		Args(buffer, 13).Rets(2),
+		Args(buffer, 14).Rets(2),
+		Args(buffer, 4).Rets(6),
+		Args(buffer, 7).Rets(4),
+		Args(buffer, 8).Rets(11),
+		Args(buffer, 9).Rets(11),
+		Args(buffer, 11).Rets(8),
+		Args(buffer, 5).Rets(5),
+		Args("f {", 2).Rets(2),
+	)
+}
+
+func TestMoveDotBlockStartEnd(t *testing.T) {
+	buffer := "f { [a] }"
+	// Index:   012345678
+	tt.Test(t, moveDotBlockStart,
+		Args(buffer, 6).Rets(5),
+		Args(buffer, 5).Rets(4),
+		Args(buffer, 4).Rets(3),
+		Args(buffer, 3).Rets(2),
+		Args(buffer, 2).Rets(2),
+	)
+	tt.Test(t, moveDotBlockEnd,
+		Args(buffer, 5).Rets(6),
+		Args(buffer, 6).Rets(7),
+		Args(buffer, 7).Rets(8),
+		Args(buffer, 8).Rets(9),
+		Args(buffer, 9).Rets(9),
+		Args("f { a", 4).Rets(5),
+	)
+}
+
+func TestMoveDotSOLNonBlank(t *testing.T) {
+	buffer := "ab\n \tcd"
+	// Index:   01 2 3 4567
+	tt.Test(t, moveDotSOLNonBlank,
+		Args(buffer, 1).Rets(0),
+		Args(buffer, 3).Rets(5),
+		Args(buffer, 7).Rets(5),
+		Args("  ", 1).Rets(2),
+	)
+}
+
+func TestMoveDotRightWordEnd(t *testing.T) {
+	buffer := "cd ~/tmp;  ls"
+	// Index:   0123456789012
+	tt.Test(t, moveDotRightWordEnd,
+		Args(buffer, 0).Rets(1),
+		Args(buffer, 1).Rets(8),
+		Args(buffer, 8).Rets(12),
+		Args(buffer, 12).Rets(12),
+	)
+	tt.Test(t, moveDotRightSmallWordEnd,
+		Args(buffer, 1).Rets(4),
+		Args(buffer, 4).Rets(7),
+		Args(buffer, 7).Rets(8),
+		Args(buffer, 8).Rets(12),
+	)
+	tt.Test(t, moveDotRightAlnumWordEnd,
+		Args(buffer, 1).Rets(7),
+		Args(buffer, 7).Rets(12),
+	)
+}
+
+func TestFindRune(t *testing.T) {
+	buffer := "echo a,b,c\nd,e"
+	// Index:   0123456789 0123
+	// + 10 *   0          1
+	forward := func(count int, till bool) findRuneOpts {
+		return findRuneOpts{Count: count, Till: till}
+	}
+	backward := func(count int, till bool) findRuneOpts {
+		return findRuneOpts{Count: count, Backward: true, Till: till}
+	}
+	tt.Test(t, findRune,
+		Args(buffer, 0, ",", forward(1, false)).Rets(6, true),
+		Args(buffer, 0, ",", forward(2, false)).Rets(8, true),
+		Args(buffer, 0, ",", forward(1, true)).Rets(5, true),
+		// The rune at the dot is skipped.
+		Args(buffer, 6, ",", forward(1, false)).Rets(8, true),
+		// Only the line of the dot is searched.
+		Args(buffer, 0, ",", forward(3, false)).Rets(0, false),
+		Args(buffer, 10, ",", forward(1, false)).Rets(0, false),
+		Args(buffer, 10, ",", backward(1, false)).Rets(8, true),
+		Args(buffer, 10, ",", backward(2, true)).Rets(7, true),
+		Args(buffer, 13, ",", backward(2, false)).Rets(0, false),
+		Args(buffer, 0, "", forward(1, false)).Rets(0, false),
+	)
+}
+
+func TestTextObject(t *testing.T) {
+	tt.Test(t, textObject,
+		// Words
+		Args("echo foo-bar  x", 6, "iw").Rets(5, 8, true, nil),
+		Args("echo foo-bar  x", 6, "iW").Rets(5, 12, true, nil),
+		Args("echo foo-bar  x", 6, "aW").Rets(5, 14, true, nil),
+		Args("echo foo", 6, "aw").Rets(4, 8, true, nil),
+		Args("echo  foo", 4, "iw").Rets(4, 6, true, nil),
+		Args("echo  foo", 4, "aw").Rets(4, 9, true, nil),
+		Args("echo", 4, "iw").Rets(0, 4, true, nil),
+		Args("", 0, "iw").Rets(0, 0, false, nil),
+		// Quotes
+		Args(`echo "a\"b" x`, 7, `i"`).Rets(6, 10, true, nil),
+		Args(`echo "a\"b" x`, 7, `a"`).Rets(5, 12, true, nil),
+		Args(`echo "ab"`, 7, `a"`).Rets(4, 9, true, nil),
+		Args(`echo x 'ab'`, 0, `i'`).Rets(8, 10, true, nil),
+		Args(`echo 'ab' x`, 10, `i'`).Rets(0, 0, false, nil),
+		// Brackets
+		Args("f (g [a b] ?(c))", 7, "i[").Rets(6, 9, true, nil),
+		Args("f (g [a b] ?(c))", 7, "a]").Rets(5, 10, true, nil),
+		Args("f (g [a b] ?(c))", 7, "ib").Rets(3, 15, true, nil),
+		Args("f (g [a b] ?(c))", 13, "a(").Rets(11, 15, true, nil),
+		Args("f (g [a b] ?(c))", 2, "i(").Rets(3, 15, true, nil),
+		Args("f { 'a}' }", 6, "i{").Rets(3, 9, true, nil),
+		Args("f (g", 3, "i(").Rets(0, 0, false, nil),
+		// Bad names
+		Args("", 0, "iz").Rets(0, 0, false, errBadTextObject),
+		Args("", 0, "xw").Rets(0, 0, false, errBadTextObject),
+	)
+}
+
 func TestMoveDotUpDown(t *testing.T) {
 	buffer := "abc\n精灵语\ndef"
 	// Index:
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"src.elv.sh/pkg/cli"
 	"src.elv.sh/pkg/cli/histutil"
@@ -11,6 +12,7 @@ import (
 	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/eval/vars"
+	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/store/storedefs"
 )
 
@@ -41,20 +43,39 @@ func initAfterReadline(appSpec *cli.AppSpec, ev *eval.Evaler, nb eval.NsBuilder)
 	})
 }
 
-func initAddCmdFilters(appSpec *cli.AppSpec, ev *eval.Evaler, nb eval.NsBuilder, s histutil.Store) {
+func initAddCmdFilters(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, nb eval.NsBuilder, s histutil.Store) {
 	ignoreLeadingSpace := eval.NewGoFn("<ignore-cmd-with-leading-space>",
 		func(s string) bool { return !strings.HasPrefix(s, " ") })
 	filters := newListVar(vals.MakeList(ignoreLeadingSpace))
 	nb.AddVar("add-cmd-filters", filters)
 
+	// The sequence number and metadata of the command just added to the
+	// history. The metadata is completed and saved after the command finishes.
+	lastSeq := -1
+	var lastMeta storedefs.CmdMeta
 	appSpec.AfterReadline = append(appSpec.AfterReadline, func(code string) {
+		lastSeq = -1
 		if code != "" &&
 			callFilters(ev, "$<edit>:add-cmd-filters",
 				filters.Get().(vals.List), code) {
-			s.AddCmd(storedefs.Cmd{Text: code, Seq: -1})
+			seq, err := s.AddCmd(storedefs.Cmd{Text: code, Seq: -1})
+			if err == nil {
+				dir, _ := os.Getwd()
+				lastSeq, lastMeta = seq, storedefs.CmdMeta{Dir: dir, Start: time.Now()}
+			}
 		}
 		// TODO(xiaq): Handle the error.
 	})
+	ed.AfterCommand = append(ed.AfterCommand,
+		func(src parse.Source, duration float64, err error) {
+			if lastSeq == -1 {
+				return
+			}
+			lastMeta.Status = eval.ExitStatus(err)
+			lastMeta.Duration = time.Duration(duration * float64(time.Second))
+			s.SetCmdMeta(lastSeq, lastMeta)
+			lastSeq = -1
+		})
 }
 
 func initGlobalBindings(appSpec *cli.AppSpec, nt notifier, ev *eval.Evaler, nb eval.NsBuilder) {
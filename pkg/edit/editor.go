@@ -34,6 +34,9 @@ type Editor struct {
 	// edit:completion:smart-start to apply the autofix easily. This field is
 	// set in initHighlighter.
 	applyAutofix func()
+	// Returns the string used for one level of indentation. This field is set
+	// in initInsertAPI.
+	indent func() string
 
 	// Maybe move this to another type that represents the REPL cycle as a whole, not just the
 	// read/edit portion represented by the Editor type.
@@ -68,10 +71,11 @@ func NewEditor(tty cli.TTY, ev *eval.Evaler, st storedefs.Store) *Editor {
 
 	initMaxHeight(&appSpec, nb)
 	initReadlineHooks(&appSpec, ev, nb)
-	initAddCmdFilters(&appSpec, ev, nb, hs)
+	initAddCmdFilters(&appSpec, ed, ev, nb, hs)
 	initGlobalBindings(&appSpec, ed, ev, nb)
 	initInsertAPI(&appSpec, ed, ev, nb)
 	initHighlighter(&appSpec, ed, ev, nb)
+	initAutosuggest(&appSpec, ed, ev, nb, hs)
 	initPrompts(&appSpec, ed, ev, nb)
 	ed.app = cli.NewApp(appSpec)
 
@@ -1,9 +1,14 @@
 package edit
 
 import (
+	"errors"
+	"os"
 	"reflect"
 	"testing"
+	"time"
 
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/store/storedefs"
 	"src.elv.sh/pkg/ui"
 )
@@ -26,6 +31,31 @@ func TestEditor_DoesNotAddEmptyCommandToHistory(t *testing.T) {
 	testCommands(t, f.Store /* no commands */)
 }
 
+func TestEditor_AddsHistoryMetadataAfterCommand(t *testing.T) {
+	f := setup(t)
+
+	feedInput(f.TTYCtrl, "fail x\n")
+	f.Wait()
+	f.Editor.RunAfterCommandHooks(parse.Source{}, 1.5, errors.New("x"))
+
+	meta, err := f.Store.CmdMeta(1)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if wd := must.OK1(os.Getwd()); meta.Dir != wd {
+		t.Errorf("got dir %q, want %q", meta.Dir, wd)
+	}
+	if meta.Status != 1 {
+		t.Errorf("got status %v, want 1", meta.Status)
+	}
+	if meta.Start.IsZero() {
+		t.Errorf("got zero start time")
+	}
+	if meta.Duration != 1500*time.Millisecond {
+		t.Errorf("got duration %v, want 1.5s", meta.Duration)
+	}
+}
+
 func TestEditor_Notify(t *testing.T) {
 	f := setup(t)
 	f.Editor.Notify(ui.T("note"))
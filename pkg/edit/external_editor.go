@@ -0,0 +1,72 @@
+package edit
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+
+	"src.elv.sh/pkg/cli"
+	"src.elv.sh/pkg/cli/tk"
+	"src.elv.sh/pkg/env"
+)
+
+var errNoEditor = errors.New("environment variable EDITOR is not set")
+
+// Runs the editor, which may contain arguments separated by spaces, on the file
+// at the given path. Can be overridden in tests.
+var runEditor = func(editor, path string) error {
+	words := strings.Fields(editor)
+	cmd := exec.Command(words[0], append(words[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// Edits the content of the code area with the editor in $E:EDITOR, like
+// Ctrl-X Ctrl-E in Bash. The code area is left unchanged if the editor exits
+// with an error.
+func editInExternalEditor(app cli.App) error {
+	codeArea, ok := focusedCodeArea(app)
+	if !ok {
+		return nil
+	}
+	editor := os.Getenv(env.EDITOR)
+	if strings.TrimSpace(editor) == "" {
+		return errNoEditor
+	}
+
+	// Use the .elv extension, so that editors can highlight the code.
+	file, err := os.CreateTemp("", "elvish-*.elv")
+	if err != nil {
+		return err
+	}
+	path := file.Name()
+	defer os.Remove(path)
+	_, err = file.WriteString(codeArea.CopyState().Buffer.Content)
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return err
+	}
+
+	var errRun error
+	err = app.Suspend(func() { errRun = runEditor(editor, path) })
+	if err != nil {
+		return err
+	}
+	if errRun != nil {
+		return errRun
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	// Most editors end files with a newline, which is not part of the code.
+	code := strings.TrimRight(string(content), "\r\n")
+	codeArea.MutateState(func(s *tk.CodeAreaState) {
+		s.Buffer = tk.CodeBuffer{Content: code, Dot: len(code)}
+	})
+	return nil
+}
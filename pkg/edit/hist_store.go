@@ -26,6 +26,12 @@ func (s *histStore) AddCmd(cmd storedefs.Cmd) (int, error) {
 	return s.hs.AddCmd(cmd)
 }
 
+func (s *histStore) SetCmdMeta(seq int, meta storedefs.CmdMeta) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.hs.SetCmdMeta(seq, meta)
+}
+
 // AllCmds returns a slice of all interactive commands in oldest to newest order.
 func (s *histStore) AllCmds() ([]storedefs.Cmd, error) {
 	s.m.Lock()
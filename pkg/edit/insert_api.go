@@ -7,7 +7,7 @@ import (
 	"src.elv.sh/pkg/eval/vars"
 )
 
-func initInsertAPI(appSpec *cli.AppSpec, nt notifier, ev *eval.Evaler, nb eval.NsBuilder) {
+func initInsertAPI(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 	simpleAbbr := vals.EmptyMap
 	simpleAbbrVar := vars.FromPtr(&simpleAbbr)
 	appSpec.SimpleAbbreviations = makeMapIterator(simpleAbbrVar)
@@ -21,11 +21,19 @@ func initInsertAPI(appSpec *cli.AppSpec, nt notifier, ev *eval.Evaler, nb eval.N
 	appSpec.SmallWordAbbreviations = makeMapIterator(smallWordAbbrVar)
 
 	bindingVar := newBindingVar(emptyBindingsMap)
-	appSpec.CodeAreaBindings = newMapBindings(nt, ev, bindingVar)
+	appSpec.CodeAreaBindings = newMapBindings(ed, ev, bindingVar)
 
 	quotePaste := newBoolVar(false)
 	appSpec.QuotePaste = func() bool { return quotePaste.GetRaw().(bool) }
 
+	indent := "  "
+	indentVar := vars.FromPtr(&indent)
+	ed.indent = func() string { return indentVar.GetRaw().(string) }
+	appSpec.Indent = ed.indent
+
+	autoPair := newBoolVar(false)
+	appSpec.AutoPair = func() bool { return autoPair.GetRaw().(bool) }
+
 	toggleQuotePaste := func() {
 		quotePaste.Set(!quotePaste.Get().(bool))
 	}
@@ -36,7 +44,9 @@ func initInsertAPI(appSpec *cli.AppSpec, nt notifier, ev *eval.Evaler, nb eval.N
 	nb.AddGoFn("toggle-quote-paste", toggleQuotePaste)
 	nb.AddNs("insert", eval.BuildNs().
 		AddVar("binding", bindingVar).
-		AddVar("quote-paste", quotePaste))
+		AddVar("quote-paste", quotePaste).
+		AddVar("indent", indentVar).
+		AddVar("auto-pair", autoPair))
 }
 
 func makeMapIterator(mv vars.PtrVar) func(func(a, b string)) {
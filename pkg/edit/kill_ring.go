@@ -0,0 +1,95 @@
+package edit
+
+import (
+	"sync"
+
+	"src.elv.sh/pkg/cli/tk"
+)
+
+// Maximum number of entries kept in the kill ring.
+const killRingSize = 60
+
+// A kill ring like readline's. Text deleted by the kill builtins is saved in
+// the ring, and can be inserted back with yank and yank-pop.
+type killRing struct {
+	mu      sync.Mutex
+	entries []string
+	// The buffer after the last kill. A kill from this buffer is a consecutive
+	// kill, which adds to the last entry instead of creating a new one.
+	lastKill tk.CodeBuffer
+	// The buffer after the last yank or yank-pop, the position where the
+	// yanked text starts, and the index of the entry yanked. Used by yank-pop
+	// to replace the yanked text.
+	lastYank  tk.CodeBuffer
+	yankFrom  int
+	yankIndex int
+}
+
+func makeKillToRing(kr *killRing, m pureMover) func(*tk.CodeBuffer) {
+	kill := makeKill(m)
+	return func(buf *tk.CodeBuffer) {
+		before := *buf
+		newDot := m(buf.Content, buf.Dot)
+		if newDot == buf.Dot {
+			return
+		}
+		kill(buf)
+		kr.mu.Lock()
+		defer kr.mu.Unlock()
+		consecutive := len(kr.entries) > 0 && before == kr.lastKill
+		switch {
+		case consecutive && newDot < before.Dot:
+			// Killing leftwards, like with repeated kill-word-left.
+			last := &kr.entries[len(kr.entries)-1]
+			*last = before.Content[newDot:before.Dot] + *last
+		case consecutive:
+			last := &kr.entries[len(kr.entries)-1]
+			*last += before.Content[before.Dot:newDot]
+		case newDot < before.Dot:
+			kr.push(before.Content[newDot:before.Dot])
+		default:
+			kr.push(before.Content[before.Dot:newDot])
+		}
+		kr.lastKill = *buf
+	}
+}
+
+// Adds an entry to the ring, dropping the oldest entry if the ring is full.
+// This method assumes the mutex is held.
+func (kr *killRing) push(text string) {
+	kr.entries = append(kr.entries, text)
+	if len(kr.entries) > killRingSize {
+		kr.entries = kr.entries[len(kr.entries)-killRingSize:]
+	}
+}
+
+// Inserts the last killed text at the dot.
+func (kr *killRing) yank(buf *tk.CodeBuffer) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if len(kr.entries) == 0 {
+		return
+	}
+	kr.yankFrom = buf.Dot
+	kr.yankIndex = len(kr.entries) - 1
+	buf.InsertAtDot(kr.entries[kr.yankIndex])
+	kr.lastYank = *buf
+}
+
+// Replaces the text inserted by the last yank or yank-pop with the entry
+// killed before it, rotating to the last entry after the first one. Does
+// nothing if the buffer has changed since the last yank or yank-pop.
+func (kr *killRing) yankPop(buf *tk.CodeBuffer) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if len(kr.entries) == 0 || *buf != kr.lastYank {
+		return
+	}
+	kr.yankIndex = (kr.yankIndex + len(kr.entries) - 1) % len(kr.entries)
+	*buf = tk.CodeBuffer{
+		Content: buf.Content[:kr.yankFrom] + buf.Content[buf.Dot:],
+		Dot:     kr.yankFrom,
+	}
+	buf.InsertAtDot(kr.entries[kr.yankIndex])
+	kr.lastYank = *buf
+}
@@ -1,6 +1,7 @@
 package edit
 
 import (
+	"fmt"
 	"os"
 
 	"src.elv.sh/pkg/cli"
@@ -34,7 +35,7 @@ func initListings(ed *Editor, ev *eval.Evaler, st storedefs.Store, histStore his
 				},
 			}))
 
-	initHistlist(ed, ev, histStore, bindingVar, nb)
+	initHistlist(ed, ev, st, histStore, bindingVar, nb)
 	initLastcmd(ed, ev, histStore, bindingVar, nb)
 	initLocation(ed, ev, st, bindingVar, nb)
 }
@@ -50,17 +51,29 @@ var filterSpec = modes.FilterSpec{
 	Highlighter: filter.Highlight,
 }
 
-func initHistlist(ed *Editor, ev *eval.Evaler, histStore histutil.Store, commonBindingVar vars.PtrVar, nb eval.NsBuilder) {
+// The maximum number of commands shown in the history listing when searching
+// the store.
+const histlistSearchLimit = 1000
+
+func initHistlist(ed *Editor, ev *eval.Evaler, st storedefs.Store, histStore histutil.Store, commonBindingVar vars.PtrVar, nb eval.NsBuilder) {
 	bindingVar := newBindingVar(emptyBindingsMap)
 	bindings := newMapBindings(ed, ev, bindingVar, commonBindingVar)
 	dedup := newBoolVar(true)
+	searchMode := vars.FromInit("filter")
 	ns := eval.BuildNsNamed("edit:histlist").
 		AddVar("binding", bindingVar).
+		AddVar("search-mode", searchMode).
 		AddGoFns(map[string]any{
 			"start": func() {
+				search, err := histlistSearch(st, vals.ToString(searchMode.Get()))
+				if err != nil {
+					startMode(ed.app, nil, err)
+					return
+				}
 				w, err := modes.NewHistlist(ed.app, modes.HistlistSpec{
 					Bindings: bindings,
 					AllCmds:  histStore.AllCmds,
+					Search:   search,
 					Dedup: func() bool {
 						return dedup.Get().(bool)
 					},
@@ -81,6 +94,27 @@ func initHistlist(ed *Editor, ev *eval.Evaler, histStore histutil.Store, commonB
 	nb.AddNs("histlist", ns)
 }
 
+// Returns the function to search the history with in the history listing,
+// according to $edit:histlist:search-mode. The function is nil when commands
+// should be filtered with filterSpec, which is the case in the default filter
+// mode or when there is no store.
+func histlistSearch(st storedefs.Store, modeName string) (func(string, bool) ([]storedefs.Cmd, error), error) {
+	if modeName == "filter" {
+		return nil, nil
+	}
+	mode, err := storedefs.ParseSearchMode(modeName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search mode %q; must be one of filter, substring, regexp and fuzzy", modeName)
+	}
+	if st == nil {
+		return nil, nil
+	}
+	return func(query string, dedup bool) ([]storedefs.Cmd, error) {
+		return st.SearchCmds(query, storedefs.SearchOpts{
+			Mode: mode, Dedup: dedup, Limit: histlistSearchLimit})
+	}, nil
+}
+
 func initLastcmd(ed *Editor, ev *eval.Evaler, histStore histutil.Store, commonBindingVar vars.PtrVar, nb eval.NsBuilder) {
 	bindingVar := newBindingVar(emptyBindingsMap)
 	bindings := newMapBindings(ed, ev, bindingVar, commonBindingVar)
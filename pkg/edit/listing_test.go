@@ -135,6 +135,30 @@ func TestHistlistAddon(t *testing.T) {
 	)
 }
 
+func TestHistlistAddon_SearchMode(t *testing.T) {
+	f := setup(t, storeOp(func(s storedefs.Store) {
+		s.AddCmd("git status")
+		s.AddCmd("echo")
+	}))
+
+	evals(f.Evaler, `set edit:histlist:search-mode = fuzzy`)
+	f.TTYCtrl.Inject(term.K('R', ui.Ctrl), term.K('g'), term.K('s'))
+	f.TestTTY(t,
+		"~> \n",
+		" HISTORY (dedup on)  gs", Styles,
+		"********************   ", term.DotHere,
+		"               Ctrl-D dedup\n", Styles,
+		"               ++++++      ",
+		"   1 git status                                   ", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++",
+	)
+
+	evals(f.Evaler, `edit:close-mode`, `set edit:histlist:search-mode = bad`)
+	f.TTYCtrl.Inject(term.K('R', ui.Ctrl))
+	f.TTYCtrl.TestMsg(t, ui.Concat(ui.T("error:", ui.FgRed),
+		ui.T(` invalid search mode "bad"; must be one of filter, substring, regexp and fuzzy`)))
+}
+
 func TestLastCmdAddon(t *testing.T) {
 	f := setup(t, storeOp(func(s storedefs.Store) {
 		s.AddCmd("echo hello world")
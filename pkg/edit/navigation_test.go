@@ -109,6 +109,8 @@ func TestNavigation_EnterDoesNotAddSpaceAtStartOfBuffer(t *testing.T) {
 // of a line buffer omits the space char prefix.
 func TestNavigation_EnterDoesNotAddSpaceAtStartOfLine(t *testing.T) {
 	f := setupNav(t)
+	// Disable auto-indentation, so that the dot is at the start of the line.
+	evals(f.Evaler, `set edit:insert:indent = ''`)
 
 	feedInput(f.TTYCtrl, "put [\n")
 	f.TTYCtrl.Inject(term.K('N', ui.Ctrl)) // begin navigation mode
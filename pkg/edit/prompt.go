@@ -21,6 +21,11 @@ func initPrompts(appSpec *cli.AppSpec, nt notifier, ev *eval.Evaler, nb eval.NsB
 	initPrompt(&appSpec.Prompt, "prompt", promptVal, nt, ev, nb)
 	initPrompt(&appSpec.RPrompt, "rprompt", rpromptVal, nt, ev, nb)
 
+	nb.AddGoFn("-trigger-prompts", func() {
+		appSpec.Prompt.Trigger(true)
+		appSpec.RPrompt.Trigger(true)
+	})
+
 	rpromptPersistentVar := newBoolVar(false)
 	appSpec.RPromptPersistent = func() bool { return rpromptPersistentVar.Get().(bool) }
 	nb.AddVar("rprompt-persistent", rpromptPersistentVar)
@@ -71,6 +71,21 @@ func TestPromptEagerness(t *testing.T) {
 	f.TestTTY(t, "2> ", term.DotHere)
 }
 
+func TestTriggerPrompts(t *testing.T) {
+	f := setup(t, rc(
+		`var i j = 0 0`,
+		`set edit:prompt = { set i = (+ $i 1); put $i'> ' }`,
+		`set edit:rprompt = { set j = (+ $j 1); put R$j }`,
+		`set edit:-prompt-eagerness = 0`,
+		`set edit:-rprompt-eagerness = 0`))
+
+	f.TestTTY(t, "1> ", term.DotHere,
+		strings.Repeat(" ", clitest.FakeTTYWidth-5)+"R1")
+	evals(f.Evaler, `edit:-trigger-prompts`)
+	f.TestTTY(t, "2> ", term.DotHere,
+		strings.Repeat(" ", clitest.FakeTTYWidth-5)+"R2")
+}
+
 func TestPromptStaleThreshold(t *testing.T) {
 	f := setup(t, rc(
 		`var pipe = (file:pipe)`,
@@ -10,7 +10,10 @@ import (
 	"src.elv.sh/pkg/eval/vars"
 )
 
-var errDotOutOfBoundary = errors.New("dot out of command boundary")
+var (
+	errDotOutOfBoundary       = errors.New("dot out of command boundary")
+	errSelectionOutOfBoundary = errors.New("selection out of command boundary")
+)
 
 func insertAtDot(app cli.App, text string) {
 	codeArea, ok := focusedCodeArea(app)
@@ -61,6 +64,18 @@ func initStateAPI(app cli.App, nb eval.NsBuilder) {
 	}
 	nb.AddVar("-dot", vars.FromSetGet(setDot, getDot))
 
+	nb.AddGoFn("-select", func(from, to int) error {
+		var err error
+		codeArea.MutateState(func(s *tk.CodeAreaState) {
+			if from < 0 || to > len(s.Buffer.Content) {
+				err = errSelectionOutOfBoundary
+			} else {
+				s.Selection = tk.Selection{From: from, To: to}
+			}
+		})
+		return err
+	})
+
 	setCurrentCommand := func(v any) error {
 		var content string
 		err := vals.ScanToGo(v, &content)
@@ -41,6 +41,26 @@ func TestDotOutOfBoundary(t *testing.T) {
 	testGlobal(t, f.Evaler, "err", errDotOutOfBoundary)
 }
 
+func TestSelect(t *testing.T) {
+	f := setup(t)
+
+	f.SetCodeBuffer(tk.CodeBuffer{Content: "code", Dot: 4})
+	evals(f.Evaler, `edit:-select 1 3`)
+
+	wantSelection := tk.Selection{From: 1, To: 3}
+	if sel := codeArea(f.Editor.app).CopyState().Selection; sel != wantSelection {
+		t.Errorf("selection = %v, want %v", sel, wantSelection)
+	}
+}
+
+func TestSelectOutOfBoundary(t *testing.T) {
+	f := setup(t)
+
+	f.SetCodeBuffer(tk.CodeBuffer{Content: "", Dot: 0})
+	evals(f.Evaler, "var err = ?(edit:-select 0 10)[reason]")
+	testGlobal(t, f.Evaler, "err", errSelectionOutOfBoundary)
+}
+
 func TestCurrentCommand(t *testing.T) {
 	f := setup(t)
 
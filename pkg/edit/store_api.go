@@ -7,7 +7,6 @@ import (
 	"src.elv.sh/pkg/cli/histutil"
 	"src.elv.sh/pkg/cli/tk"
 	"src.elv.sh/pkg/eval"
-	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/parse/parseutil"
 	"src.elv.sh/pkg/store/storedefs"
 )
@@ -40,7 +39,7 @@ func commandHistory(opts cmdhistOpt, fuser histutil.Store, out eval.ValueOutput)
 		}
 	} else {
 		for _, cmd := range cmds {
-			err := out.Put(vals.MakeMap("id", cmd.Seq, "cmd", cmd.Text))
+			err := out.Put(cmd.Map())
 			if err != nil {
 				return err
 			}
@@ -2,6 +2,7 @@ package edit
 
 import (
 	"testing"
+	"time"
 
 	"src.elv.sh/pkg/cli/tk"
 	"src.elv.sh/pkg/eval/vals"
@@ -81,6 +82,25 @@ func TestCommandHistory(t *testing.T) {
 	testThatOutputErrorIsBubbled(t, f, "edit:command-history &cmd-only")
 }
 
+func TestCommandHistory_Metadata(t *testing.T) {
+	f := setup(t, storeOp(func(s storedefs.Store) {
+		s.AddCmd("echo 0")
+		s.AddCmd("false")
+		s.SetCmdMeta(2, storedefs.CmdMeta{
+			Dir: "/home/elf", Status: 1,
+			Start: time.Unix(1700000000, 5e8), Duration: 2 * time.Second})
+	}))
+
+	evals(f.Evaler, `var @cmds = (edit:command-history)`)
+	testGlobal(t, f.Evaler,
+		"cmds",
+		vals.MakeList(
+			cmdMap(1, "echo 0"),
+			vals.MakeMap("id", 2, "cmd", "false", "dir", "/home/elf",
+				"status", 1, "start", 1700000000.5, "duration", 2.0),
+		))
+}
+
 func cmdMap(id int, cmd string) vals.Map {
 	return vals.MakeMap("id", id, "cmd", cmd)
 }
@@ -0,0 +1,461 @@
+// Package elvfmt implements a formatter for Elvish source code.
+//
+// The formatter works on the parse tree and only changes whitespace; comments
+// and the text of leaf nodes like strings are preserved verbatim. The rules
+// are:
+//
+//   - Indentation uses two spaces per level.
+//
+//   - The body of a lambda or a capture is written on one line (like
+//     "{ echo foo; echo bar }") if it doesn't contain any newline, or as an
+//     indented block otherwise. The same applies to lists and maps, whose
+//     elements keep their original line breaks when written as a block.
+//
+//   - Forms have their arguments separated by a single space; forms in a
+//     pipeline are separated by " | ". Lines continued with "^" or after "|"
+//     are indented one more level.
+//
+//   - Consecutive blank lines are collapsed into one, and blank lines at the
+//     start and end of blocks are removed.
+package elvfmt
+
+import (
+	"slices"
+	"strings"
+
+	"src.elv.sh/pkg/parse"
+)
+
+const indentUnit = "  "
+
+// Format formats Elvish source code. It returns an error if the code can't be
+// parsed.
+func Format(src parse.Source) (string, error) {
+	tree, err := parse.Parse(src, parse.Config{})
+	if err != nil {
+		return "", err
+	}
+	p := printer{atLineStart: true}
+	p.block(parse.Children(tree.Root), true)
+	p.lineBreak()
+	return p.sb.String(), nil
+}
+
+type printer struct {
+	sb     strings.Builder
+	indent int
+	// Whether nothing has been written on the current line.
+	atLineStart bool
+	// Whether a space should be written before the next token on the same
+	// line.
+	space bool
+	// Whether the current line ends with a comment, in which case nothing
+	// more can be written on it.
+	afterComment bool
+}
+
+func (p *printer) write(s string) {
+	if s == "" {
+		return
+	}
+	if p.afterComment {
+		p.newline()
+	}
+	if p.atLineStart {
+		p.sb.WriteString(strings.Repeat(indentUnit, p.indent))
+		p.atLineStart = false
+	} else if p.space {
+		p.sb.WriteByte(' ')
+	}
+	p.space = false
+	p.sb.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.sb.WriteByte('\n')
+	p.atLineStart, p.space, p.afterComment = true, false, false
+}
+
+// lineBreak starts a new line unless the current line is empty.
+func (p *printer) lineBreak() {
+	if !p.atLineStart {
+		p.newline()
+	}
+}
+
+func (p *printer) comment(text string) {
+	p.space = true
+	p.write(strings.TrimRight(text, " \t\r"))
+	p.afterComment = true
+}
+
+// block writes children as a sequence of lines. Items are written on the same
+// line when they are on the same line in the source, unless splitAll is true.
+// Items in a block are written on new lines, even if the first item follows
+// the opening bracket in the source.
+func (p *printer) block(children []parse.Node, splitAll bool) {
+	// Number of line breaks since the last item or comment.
+	newlines := 0
+	started := false
+	prevIsItem := false
+	startLine := func() {
+		p.lineBreak()
+		if newlines >= 2 && started {
+			p.newline()
+		}
+	}
+	for _, ch := range children {
+		if sep, ok := ch.(*parse.Sep); ok {
+			for _, t := range lexSep(parse.SourceText(sep)) {
+				switch t.typ {
+				case newlineToken, continuationToken:
+					newlines++
+				case punctToken:
+					// Only semicolons in chunks can appear here.
+					if newlines == 0 {
+						newlines = 1
+					}
+				case commentToken:
+					if newlines > 0 {
+						startLine()
+					}
+					p.comment(t.text)
+					newlines, started, prevIsItem = 0, true, false
+				}
+			}
+			continue
+		}
+		if newlines > 0 || splitAll || !prevIsItem {
+			startLine()
+		} else {
+			p.space = true
+		}
+		p.node(ch)
+		newlines, started, prevIsItem = 0, true, true
+	}
+}
+
+func (p *printer) node(n parse.Node) {
+	switch n := n.(type) {
+	case *parse.Pipeline:
+		p.pipeline(n)
+	case *parse.Compound:
+		p.compound(n)
+	case *parse.MapPair:
+		p.mapPair(n)
+	default:
+		p.write(parse.SourceText(n))
+	}
+}
+
+// Writes a chunk that doesn't contain any newline.
+func (p *printer) inlineChunk(n *parse.Chunk) {
+	for i, pn := range n.Pipelines {
+		if i > 0 {
+			p.write(";")
+			p.space = true
+		}
+		p.pipeline(pn)
+	}
+}
+
+// Writes the body of a lambda or capture, followed by the closing delimiter.
+// The opening delimiter has already been written, and seps contains the
+// separators between it and the chunk.
+func (p *printer) body(seps []parse.Node, n *parse.Chunk, spaced bool, closer string) {
+	if hasNewline(n) || slices.ContainsFunc(seps, hasNewline) {
+		p.indent++
+		p.block(append(seps, parse.Children(n)...), true)
+		p.indent--
+		p.lineBreak()
+	} else {
+		p.space = spaced
+		p.inlineChunk(n)
+		p.space = spaced
+	}
+	p.write(closer)
+}
+
+func (p *printer) pipeline(n *parse.Pipeline) {
+	indented := false
+	for _, ch := range parse.Children(n) {
+		switch ch := ch.(type) {
+		case *parse.Form:
+			p.form(ch)
+		case *parse.Sep:
+			for _, t := range lexSep(parse.SourceText(ch)) {
+				switch t.typ {
+				case punctToken:
+					// "|" or the background indicator "&".
+					p.space = true
+					p.write(t.text)
+					p.space = t.text == "|"
+				case commentToken:
+					p.comment(t.text)
+				case newlineToken, continuationToken:
+					p.lineBreak()
+					if !indented {
+						p.indent++
+						indented = true
+					}
+				}
+			}
+		}
+	}
+	if indented {
+		p.indent--
+	}
+}
+
+func (p *printer) form(n *parse.Form) {
+	indented := false
+	// Line continuations are only written when followed by another item of
+	// the form; a trailing one is redundant.
+	continued := false
+	for i, ch := range parse.Children(n) {
+		if sep, ok := ch.(*parse.Sep); ok {
+			for _, t := range lexSep(parse.SourceText(sep)) {
+				switch t.typ {
+				case continuationToken:
+					continued = true
+				case commentToken:
+					p.comment(t.text)
+				}
+			}
+			continue
+		}
+		if continued {
+			p.space = true
+			p.write("^")
+			p.newline()
+			if !indented {
+				p.indent++
+				indented = true
+			}
+			continued = false
+		}
+		if i > 0 {
+			p.space = true
+		}
+		switch ch := ch.(type) {
+		case *parse.Compound:
+			p.compound(ch)
+		case *parse.MapPair:
+			p.mapPair(ch)
+		case *parse.Redir:
+			p.redir(ch)
+		}
+	}
+	if indented {
+		p.indent--
+	}
+}
+
+func (p *printer) redir(n *parse.Redir) {
+	if n.Left != nil {
+		p.compound(n.Left)
+	}
+	for _, ch := range parse.Children(n) {
+		if sep, ok := ch.(*parse.Sep); ok {
+			p.write(strings.TrimSpace(parse.SourceText(sep)))
+			break
+		}
+	}
+	if n.RightIsFd {
+		p.write("&")
+	} else {
+		p.space = true
+	}
+	p.compound(n.Right)
+}
+
+func (p *printer) mapPair(n *parse.MapPair) {
+	if hasComment(n) {
+		p.write(parse.SourceText(n))
+		return
+	}
+	p.write("&")
+	p.compound(n.Key)
+	if n.Value != nil {
+		p.write("=")
+		p.compound(n.Value)
+	}
+}
+
+func (p *printer) compound(n *parse.Compound) {
+	for _, in := range n.Indexings {
+		p.primary(in.Head)
+		for _, index := range in.Indices {
+			p.write("[")
+			if hasNewline(index) {
+				p.write(strings.TrimSpace(parse.SourceText(index)))
+			} else {
+				for i, cn := range index.Compounds {
+					p.space = i > 0
+					p.compound(cn)
+				}
+			}
+			p.write("]")
+		}
+	}
+}
+
+func (p *printer) primary(n *parse.Primary) {
+	switch n.Type {
+	case parse.Lambda:
+		p.lambda(n)
+	case parse.OutputCapture:
+		p.write("(")
+		p.body(nil, n.Chunk, false, ")")
+	case parse.ExceptionCapture:
+		p.write("?(")
+		p.body(nil, n.Chunk, false, ")")
+	case parse.List, parse.Map:
+		p.listOrMap(n)
+	default:
+		p.write(parse.SourceText(n))
+	}
+}
+
+func (p *printer) lambda(n *parse.Primary) {
+	// Children between "{" and the body.
+	var head []parse.Node
+	hasParams := false
+	for _, ch := range parse.Children(n)[1:] {
+		if ch == n.Chunk {
+			break
+		}
+		head = append(head, ch)
+		if sep, ok := ch.(*parse.Sep); ok && parse.SourceText(sep) == "|" {
+			hasParams = true
+		}
+	}
+	if !hasParams {
+		// The whitespace after "{" is part of the body.
+		p.write("{")
+		p.body(head, n.Chunk, true, "}")
+		return
+	}
+	if slices.ContainsFunc(head, hasComment) {
+		// A comment among the parameters, which is rare enough to not be
+		// worth handling.
+		p.write(parse.SourceText(n))
+		return
+	}
+	p.write("{|")
+	first := true
+	for _, ch := range head {
+		if _, ok := ch.(*parse.Sep); !ok {
+			p.space = !first
+			p.node(ch)
+			first = false
+		}
+	}
+	p.write("|")
+	p.body(nil, n.Chunk, true, "}")
+}
+
+func (p *printer) listOrMap(n *parse.Primary) {
+	children := parse.Children(n)
+	// Strip the brackets.
+	inner := children[1 : len(children)-1]
+	if n.Type == parse.Map && len(n.MapPairs) == 0 {
+		if hasComment(n) {
+			p.write(parse.SourceText(n))
+		} else {
+			p.write("[&]")
+		}
+		return
+	}
+	multiline := false
+	for _, ch := range inner {
+		if _, ok := ch.(*parse.Sep); ok && strings.ContainsAny(parse.SourceText(ch), "\n#") {
+			multiline = true
+		}
+	}
+	p.write("[")
+	if multiline {
+		p.indent++
+		p.block(inner, false)
+		p.indent--
+		p.lineBreak()
+	} else {
+		first := true
+		for _, ch := range inner {
+			if _, ok := ch.(*parse.Sep); !ok {
+				p.space = !first
+				p.node(ch)
+				first = false
+			}
+		}
+	}
+	p.write("]")
+}
+
+// Reports whether any separator in the tree rooted at n contains a newline.
+func hasNewline(n parse.Node) bool {
+	return anySep(n, func(s string) bool { return strings.Contains(s, "\n") })
+}
+
+// Reports whether any separator in the tree rooted at n contains a comment.
+func hasComment(n parse.Node) bool {
+	return anySep(n, func(s string) bool { return strings.Contains(s, "#") })
+}
+
+func anySep(n parse.Node, f func(string) bool) bool {
+	if _, ok := n.(*parse.Sep); ok {
+		return f(parse.SourceText(n))
+	}
+	for _, ch := range parse.Children(n) {
+		if anySep(ch, f) {
+			return true
+		}
+	}
+	return false
+}
+
+type tokenType int
+
+const (
+	newlineToken tokenType = iota
+	// "^" followed by a newline.
+	continuationToken
+	commentToken
+	// Any other character that is not whitespace.
+	punctToken
+)
+
+type token struct {
+	typ  tokenType
+	text string
+}
+
+// lexSep splits the text of a separator into tokens, dropping inline
+// whitespace.
+func lexSep(s string) []token {
+	var tokens []token
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\n':
+			tokens = append(tokens, token{newlineToken, "\n"})
+			i++
+		case s[i] == ' ' || s[i] == '\t' || s[i] == '\r':
+			i++
+		case s[i] == '#':
+			j := strings.IndexByte(s[i:], '\n')
+			if j == -1 {
+				j = len(s) - i
+			}
+			tokens = append(tokens, token{commentToken, s[i : i+j]})
+			i += j
+		case s[i] == '^' && strings.HasPrefix(strings.TrimPrefix(s[i+1:], "\r"), "\n"):
+			tokens = append(tokens, token{continuationToken, "^"})
+			i += strings.IndexByte(s[i:], '\n') + 1
+		default:
+			// Separators don't contain multi-byte characters outside comments.
+			tokens = append(tokens, token{punctToken, s[i : i+1]})
+			i++
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,182 @@
+package elvfmt
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"src.elv.sh/pkg/diff"
+	"src.elv.sh/pkg/parse"
+)
+
+var formatTests = []struct {
+	name string
+	code string
+	want string
+}{
+	{"empty", "", ""},
+	{"spaces between arguments", "echo  a\tb   c  ", "echo a b c\n"},
+	{"pipeline", "echo a|each {|x|put $x}|   slurp", "echo a | each {|x| put $x } | slurp\n"},
+	{"background", "sleep 1 &", "sleep 1 &\n"},
+	{"pipeline continuation",
+		"echo a |\neach $put~ |\n  slurp",
+		"echo a |\n  each $put~ |\n  slurp\n"},
+	{"line continuation",
+		"ls ^\n-l ^\n     -a",
+		"ls ^\n  -l ^\n  -a\n"},
+	{"trailing line continuation is dropped",
+		"ls ^\n\necho", "ls\necho\n"},
+	{"semicolons are turned into newlines at top level",
+		"echo a;echo b;", "echo a\necho b\n"},
+	{"blank lines are collapsed",
+		"\n\necho a\n\n\n\necho b\n\n", "echo a\n\necho b\n"},
+
+	{"inline lambda", "f { echo;echo  }", "f { echo; echo }\n"},
+	{"inline lambda with parameters", "f {|a @b &k=v|put $a}", "f {|a @b &k=v| put $a }\n"},
+	{"empty lambda", "f {    }", "f { }\n"},
+	{"block lambda",
+		"fn f {|x|\necho $x\n      if $x {\nput\n}}",
+		"fn f {|x|\n  echo $x\n  if $x {\n    put\n  }\n}\n"},
+	{"lambda with blank lines at start and end",
+		"f {\n\n\necho\n\n}", "f {\n  echo\n}\n"},
+	{"inline captures", "echo ( put  a ) ?( fail x )", "echo (put a) ?(fail x)\n"},
+	{"block capture",
+		"echo (\nput a\nput b)", "echo (\n  put a\n  put b\n)\n"},
+
+	{"inline list", "put [ a  b\tc ]", "put [a b c]\n"},
+	{"block list keeps line breaks",
+		"put [a b\n  c\n\n\nd]", "put [\n  a b\n  c\n\n  d\n]\n"},
+	{"inline map", "put [ &a= b &c=d ]", "put [&a=b &c=d]\n"},
+	{"empty map", "put [ & ]", "put [&]\n"},
+	{"block map",
+		"var m = [&a=[\n&b=c\n    &d=e]]",
+		"var m = [&a=[\n  &b=c\n  &d=e\n]]\n"},
+	{"indices", "put $a[ 0 ] $a[1 2][x]", "put $a[0] $a[1 2][x]\n"},
+	{"options", "f &k= v &b", "f &k=v &b\n"},
+	{"redirections",
+		"echo a >  file 2>&1 <  in >>append",
+		"echo a > file 2>&1 < in >> append\n"},
+
+	{"leaves are preserved",
+		"echo 'a  b' \"c\\n  d\" $@x *.go ~/x {a,b}",
+		"echo 'a  b' \"c\\n  d\" $@x *.go ~/x {a,b}\n"},
+	{"multi-line strings are preserved",
+		"f {\necho 'a\n  b'\n}", "f {\n  echo 'a\n  b'\n}\n"},
+
+	{"comments",
+		"# header\n\n\necho a   # trailing\n   # own line\necho b",
+		"# header\n\necho a # trailing\n# own line\necho b\n"},
+	{"comment after opening brace",
+		"f {   # comment\necho\n}", "f { # comment\n  echo\n}\n"},
+	{"comment before closing brace",
+		"f {\necho\n    # comment\n}", "f {\n  echo\n  # comment\n}\n"},
+	{"comment after pipe",
+		"echo a | # comment\neach $put~", "echo a | # comment\n  each $put~\n"},
+	{"comments in list",
+		"put [a # comment\n  b\n# another\n]", "put [\n  a # comment\n  b\n  # another\n]\n"},
+	{"comment at end of file without newline", "echo # comment", "echo # comment\n"},
+}
+
+func TestFormat(t *testing.T) {
+	for _, tc := range formatTests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Format(parse.Source{Name: "[test]", Code: tc.code})
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tc.want)
+			}
+			testFormatPreservesCode(t, tc.code, got)
+		})
+	}
+}
+
+func TestFormat_ParseError(t *testing.T) {
+	_, err := Format(parse.Source{Name: "[test]", Code: "echo ["})
+	if err == nil {
+		t.Errorf("got nil error, want parse error")
+	}
+}
+
+// Formats all the Elvish sources in this repo, and checks that formatting
+// preserves the code and is idempotent.
+func TestFormat_RepoSources(t *testing.T) {
+	root := filepath.Join("..", "..")
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".elv") {
+			return nil
+		}
+		code, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted, err := Format(parse.Source{Name: path, Code: string(code)})
+		if err != nil {
+			// Some test data is intentionally malformed.
+			return nil
+		}
+		t.Run(path, func(t *testing.T) {
+			testFormatPreservesCode(t, string(code), formatted)
+		})
+		return nil
+	})
+}
+
+func testFormatPreservesCode(t *testing.T, code, formatted string) {
+	t.Helper()
+	again, err := Format(parse.Source{Name: "[formatted]", Code: formatted})
+	if err != nil {
+		t.Fatalf("formatted code has parse error: %v", err)
+	}
+	if again != formatted {
+		t.Errorf("formatting is not idempotent; formatting again gives:\n%s", again)
+	}
+	if got, want := summarize(formatted), summarize(code); got != want {
+		t.Errorf("formatting changed code (-want +got):\n%s",
+			diff.Diff("want", want, "got", got))
+	}
+}
+
+// Summarizes the parse tree of code, ignoring whitespace (including trailing
+// whitespace in comments) and the difference between semicolons and newlines.
+func summarize(code string) string {
+	tree, err := parse.Parse(parse.Source{Name: "[summarize]", Code: code}, parse.Config{})
+	if err != nil {
+		return err.Error()
+	}
+	var sb, comments strings.Builder
+	var rec func(n parse.Node, indent string)
+	rec = func(n parse.Node, indent string) {
+		switch n := n.(type) {
+		case *parse.Sep:
+			for _, t := range lexSep(parse.SourceText(n)) {
+				switch {
+				case t.typ == commentToken:
+					fmt.Fprintln(&comments, strings.TrimRight(t.text, " \t\r"))
+				case t.typ == punctToken && t.text != ";":
+					fmt.Fprintf(&sb, "%s%q\n", indent, t.text)
+				}
+			}
+			return
+		case *parse.Primary:
+			switch n.Type {
+			case parse.Lambda, parse.OutputCapture, parse.ExceptionCapture, parse.List, parse.Map:
+				fmt.Fprintf(&sb, "%sPrimary %d\n", indent, n.Type)
+			default:
+				fmt.Fprintf(&sb, "%sPrimary %d %q\n", indent, n.Type, parse.SourceText(n))
+				return
+			}
+		default:
+			fmt.Fprintf(&sb, "%s%T\n", indent, n)
+		}
+		for _, ch := range parse.Children(n) {
+			rec(ch, indent+"  ")
+		}
+	}
+	rec(tree.Root, "")
+	return sb.String() + "comments:\n" + comments.String()
+}
@@ -0,0 +1,54 @@
+package elvfmt
+
+import (
+	"io"
+	"os"
+
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/prog"
+)
+
+// Program is the formatter subprogram.
+type Program struct {
+	run bool
+}
+
+func (p *Program) RegisterFlags(fs *prog.FlagSet) {
+	fs.BoolVar(&p.run, "fmt", false,
+		"Format Elvish source files, or stdin if no file is given, and write the result to stdout")
+}
+
+func (p *Program) Run(fds [3]*os.File, files []string) error {
+	if !p.run {
+		return prog.NextProgram()
+	}
+	if len(files) == 0 {
+		code, err := io.ReadAll(fds[0])
+		if err != nil {
+			return err
+		}
+		return formatTo(fds, parse.Source{Name: "[stdin]", Code: string(code)})
+	}
+	for _, file := range files {
+		code, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		err = formatTo(fds, parse.Source{Name: file, Code: string(code), IsFile: true})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatTo(fds [3]*os.File, src parse.Source) error {
+	formatted, err := Format(src)
+	if err != nil {
+		diag.ShowError(fds[2], err)
+		return prog.Exit(2)
+	}
+	_, err = io.WriteString(fds[1], formatted)
+	return err
+}
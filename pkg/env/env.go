@@ -4,6 +4,7 @@ package env
 
 // Environment variables with special significance to Elvish.
 const (
+	EDITOR    = "EDITOR"
 	HOME      = "HOME"
 	LS_COLORS = "LS_COLORS"
 	NO_COLOR  = "NO_COLOR"
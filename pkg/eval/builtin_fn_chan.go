@@ -0,0 +1,142 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"unsafe"
+
+	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/persistent/hash"
+)
+
+// Channels.
+
+func init() {
+	addBuiltinFns(map[string]any{
+		"make-chan":  makeChan,
+		"send":       send,
+		"recv":       recv,
+		"close-chan": closeChan,
+	})
+}
+
+// ErrChanClosed is thrown when sending to or receiving from a closed channel,
+// or closing a channel that is already closed.
+var ErrChanClosed = errors.New("channel closed")
+
+// A channel for passing values between concurrently running code.
+type channel struct {
+	ch chan any
+	// Closed when the channel is closed. The Go channel ch is never closed,
+	// since that would race with blocked sends.
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (*channel) Kind() string { return "chan" }
+
+// Equal compares by address.
+func (c *channel) Equal(rhs any) bool { return c == rhs }
+
+// Hash returns the hash of the address of the channel.
+func (c *channel) Hash() uint32 { return hash.Pointer(unsafe.Pointer(c)) }
+
+func (c *channel) Repr(int) string { return fmt.Sprintf("<chan %p>", c) }
+
+// Iterate receives values from the channel until it is closed. Builtins that
+// iterate over values use iterate instead, which can be interrupted.
+func (c *channel) Iterate(f func(any) bool) {
+	for {
+		v, err := c.recv(nil)
+		if err != nil || !f(v) {
+			return
+		}
+	}
+}
+
+// Like vals.Iterate, but receiving from a channel is interrupted when the
+// Context of fm is done.
+func iterate(fm *Frame, v any, f func(any) bool) error {
+	c, ok := v.(*channel)
+	if !ok {
+		return vals.Iterate(v, f)
+	}
+	for {
+		v, err := c.recv(fm.ctx.Done())
+		if err == ErrChanClosed {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !f(v) {
+			return nil
+		}
+	}
+}
+
+type makeChanOpts struct{ Size int }
+
+func (*makeChanOpts) SetDefaultOptions() {}
+
+func makeChan(opts makeChanOpts) (*channel, error) {
+	if opts.Size < 0 {
+		return nil, errs.OutOfRange{What: "size",
+			ValidLow: "0", ValidHigh: "+Inf", Actual: strconv.Itoa(opts.Size)}
+	}
+	return &channel{ch: make(chan any, opts.Size), closed: make(chan struct{})}, nil
+}
+
+func send(fm *Frame, c *channel, values ...any) error {
+	for _, v := range values {
+		select {
+		case <-c.closed:
+			// Checked first, since the select below may choose to send when
+			// the buffer has space, even if the channel is closed.
+			return ErrChanClosed
+		default:
+		}
+		select {
+		case c.ch <- v:
+		case <-c.closed:
+			return ErrChanClosed
+		case <-fm.ctx.Done():
+			return ErrInterrupted
+		}
+	}
+	return nil
+}
+
+func recv(fm *Frame, c *channel) (any, error) {
+	return c.recv(fm.ctx.Done())
+}
+
+// Receives a value, giving up with ErrInterrupted when cancel is closed.
+func (c *channel) recv(cancel <-chan struct{}) (any, error) {
+	select {
+	case v := <-c.ch:
+		return v, nil
+	case <-c.closed:
+		// Values still in the buffer can be received after the channel is
+		// closed.
+		select {
+		case v := <-c.ch:
+			return v, nil
+		default:
+			return nil, ErrChanClosed
+		}
+	case <-cancel:
+		return nil, ErrInterrupted
+	}
+}
+
+func closeChan(c *channel) error {
+	err := ErrChanClosed
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		err = nil
+	})
+	return err
+}
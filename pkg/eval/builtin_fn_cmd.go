@@ -3,8 +3,11 @@ package eval
 import (
 	"os"
 	"os/exec"
+	"strconv"
+	"strings"
 
 	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/eval/vals"
 )
 
 // Command and process control.
@@ -20,6 +23,11 @@ func init() {
 		"fg":   fg,
 		"exec": execFn,
 		"exit": exit,
+
+		// Job control
+		"jobs":   jobs,
+		"bg":     bg,
+		"disown": disown,
 	})
 }
 
@@ -53,3 +61,80 @@ func exit(fm *Frame, codes ...int) error {
 	osExit(code)
 	return nil
 }
+
+func fg(fm *Frame, args ...any) error {
+	if len(args) == 0 || isJobSpec(args[0]) {
+		j, err := jobFromArgs(fm, args)
+		if err != nil {
+			return err
+		}
+		return j.putInFg(fm.ctx)
+	}
+	pids := make([]int, len(args))
+	for i, arg := range args {
+		if err := vals.ScanToGo(arg, &pids[i]); err != nil {
+			return err
+		}
+	}
+	return fgPIDs(pids...)
+}
+
+func jobs(fm *Frame) error {
+	out := fm.ValueOutput()
+	for _, j := range fm.Evaler.jobs.all() {
+		info := j.info()
+		pids := make([]any, len(info.pids))
+		for i, pid := range info.pids {
+			pids[i] = pid
+		}
+		err := out.Put(vals.MakeMap(
+			"id", info.id, "source", info.source, "state", info.state.String(),
+			"pids", vals.MakeList(pids...)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func bg(fm *Frame, args ...any) error {
+	j, err := jobFromArgs(fm, args)
+	if err != nil {
+		return err
+	}
+	return j.resumeInBg()
+}
+
+func disown(fm *Frame, args ...any) error {
+	j, err := jobFromArgs(fm, args)
+	if err != nil {
+		return err
+	}
+	j.disown()
+	return nil
+}
+
+func isJobSpec(arg any) bool {
+	s, ok := arg.(string)
+	return ok && strings.HasPrefix(s, "%")
+}
+
+// Finds the job from the arguments to fg, bg or disown, which may be empty (for
+// the current job) or a single job specification like %1.
+func jobFromArgs(fm *Frame, args []any) (*job, error) {
+	if len(args) > 1 {
+		return nil, errs.ArityMismatch{What: "arguments", ValidLow: 0, ValidHigh: 1, Actual: len(args)}
+	}
+	id := 0
+	if len(args) == 1 {
+		s, ok := args[0].(string)
+		if ok && strings.HasPrefix(s, "%") {
+			id, _ = strconv.Atoi(s[1:])
+		}
+		if id <= 0 {
+			return nil, errs.BadValue{What: "job specification",
+				Valid: "% followed by a job ID", Actual: vals.ReprPlain(args[0])}
+		}
+	}
+	return fm.Evaler.jobs.get(id)
+}
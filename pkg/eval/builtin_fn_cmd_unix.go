@@ -55,7 +55,8 @@ func decSHLVL() {
 	os.Setenv(env.SHLVL, strconv.Itoa(i-1))
 }
 
-func fg(pids ...int) error {
+// Implements fg with PIDs.
+func fgPIDs(pids ...int) error {
 	if len(pids) == 0 {
 		return errs.ArityMismatch{What: "arguments", ValidLow: 1, ValidHigh: -1, Actual: len(pids)}
 	}
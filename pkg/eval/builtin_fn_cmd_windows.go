@@ -8,8 +8,8 @@ func execFn(...any) error {
 	return errNotSupportedOnWindows
 }
 
-// fg implements foreground job control on Windows using Job Objects.
-func fg(pids ...int) error {
+// fgPIDs implements fg with PIDs on Windows using Job Objects.
+func fgPIDs(pids ...int) error {
 	controller, err := NewJobController()
 	if err != nil {
 		return err
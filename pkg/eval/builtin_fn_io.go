@@ -2,13 +2,21 @@ package eval
 
 import (
 	"bufio"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 	"src.elv.sh/pkg/diag"
 	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/eval/vals"
@@ -50,12 +58,19 @@ func init() {
 		"slurp":           slurp,
 		"from-lines":      fromLines,
 		"from-json":       fromJSON,
+		"from-jsonl":      fromJSONL,
 		"from-terminated": fromTerminated,
+		"from-csv":        fromCSV,
+		"from-yaml":       fromYAML,
+		"from-toml":       fromTOML,
 
 		// Value to bytes
 		"to-lines":      toLines,
 		"to-json":       toJSON,
 		"to-terminated": toTerminated,
+		"to-csv":        toCSV,
+		"to-yaml":       toYAML,
+		"to-toml":       toTOML,
 	})
 }
 
@@ -199,6 +214,12 @@ func (f formatter) Format(state fmt.State, r rune) {
 		}
 		writeFmt(state, r, i)
 	case 'e', 'E', 'f', 'F', 'g', 'G':
+		if rat, ok := bigExactNum(wrapped); ok && (r == 'f' || r == 'F') {
+			// Format exact numbers without converting them to float64 first,
+			// so that decimals like 3/10 are formatted precisely.
+			writeFmtDecimal(state, rat)
+			return
+		}
 		var f float64
 		if err := vals.ScanToGo(wrapped, &f); err != nil {
 			fmt.Fprintf(state, "%%!%c(%s)", r, err.Error())
@@ -210,6 +231,53 @@ func (f formatter) Format(state fmt.State, r rune) {
 	}
 }
 
+// Returns a *big.Rat if v is a *big.Int or *big.Rat, which can't be
+// converted to float64 without losing precision.
+func bigExactNum(v any) (*big.Rat, bool) {
+	switch v := v.(type) {
+	case *big.Int:
+		return new(big.Rat).SetInt(v), true
+	case *big.Rat:
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+// Writes a *big.Rat as a decimal to State, supporting the same flags, width
+// and precision as the %f verb.
+func writeFmtDecimal(state fmt.State, r *big.Rat) {
+	prec, ok := state.Precision()
+	if !ok {
+		prec = 6
+	}
+	digits := new(big.Rat).Abs(r).FloatString(prec)
+	if state.Flag('#') && prec == 0 {
+		digits += "."
+	}
+	sign := ""
+	switch {
+	case r.Sign() < 0:
+		sign = "-"
+	case state.Flag('+'):
+		sign = "+"
+	case state.Flag(' '):
+		sign = " "
+	}
+	width, _ := state.Width()
+	pad := width - len(sign) - len(digits)
+	switch {
+	case pad <= 0:
+		fmt.Fprint(state, sign, digits)
+	case state.Flag('-'):
+		fmt.Fprint(state, sign, digits, strings.Repeat(" ", pad))
+	case state.Flag('0'):
+		fmt.Fprint(state, sign, strings.Repeat("0", pad), digits)
+	default:
+		fmt.Fprint(state, strings.Repeat(" ", pad), sign, digits)
+	}
+}
+
 // Writes to State using the flag it stores, but with a potentially different
 // verb and value.
 func writeFmt(state fmt.State, v rune, val any) {
@@ -378,6 +446,55 @@ func fromJSON(fm *Frame) error {
 	}
 }
 
+type fromJSONLOpts struct{ OnError Callable }
+
+func (*fromJSONLOpts) SetDefaultOptions() {}
+
+func fromJSONL(fm *Frame, opts fromJSONLOpts) error {
+	in := bufio.NewReader(fm.InputFile())
+	out := fm.ValueOutput()
+	for lineNumber := 1; ; lineNumber++ {
+		line, errRead := in.ReadString('\n')
+		if errRead != nil && errRead != io.EOF {
+			return errRead
+		}
+		line = strutil.ChopLineEnding(line)
+		if strings.TrimSpace(line) != "" {
+			v, err := parseJSONLine(line)
+			if err == nil {
+				err = out.Put(v)
+			} else if opts.OnError != nil {
+				err = opts.OnError.Call(fm.Fork(), []any{vals.MakeMap(
+					"line-number", lineNumber, "line", line, "error", err.Error())},
+					NoOpts)
+			} else {
+				err = fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if errRead == io.EOF {
+			return nil
+		}
+	}
+}
+
+// Parses a line that should contain exactly one JSON value.
+func parseJSONLine(line string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	var v any
+	err := dec.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("extra data after JSON value")
+	}
+	return fromJSONInterface(v)
+}
+
 // Converts a interface{} that results from json.Unmarshal to an Elvish value.
 func fromJSONInterface(v any) (any, error) {
 	switch v := v.(type) {
@@ -479,15 +596,633 @@ func toTerminated(fm *Frame, terminator string, inputs Inputs) error {
 	return errOut
 }
 
-func toJSON(fm *Frame, inputs Inputs) error {
+type toJSONOpts struct {
+	Indent   int
+	SortKeys bool
+}
+
+func (*toJSONOpts) SetDefaultOptions() {}
+
+func toJSON(fm *Frame, opts toJSONOpts, inputs Inputs) error {
+	if opts.Indent < 0 {
+		return errs.OutOfRange{What: "indent",
+			ValidLow: "0", ValidHigh: "+Inf", Actual: strconv.Itoa(opts.Indent)}
+	}
 	encoder := json.NewEncoder(fm.ByteOutput())
+	if opts.Indent > 0 {
+		encoder.SetIndent("", strings.Repeat(" ", opts.Indent))
+	}
 
 	var errEncode error
 	inputs(func(v any) {
 		if errEncode != nil {
 			return
 		}
+		if opts.SortKeys {
+			v = sortJSONKeys(v)
+		}
 		errEncode = encoder.Encode(v)
 	})
 	return errEncode
 }
+
+// Converts maps in v to Go maps, whose keys are sorted by encoding/json.
+func sortJSONKeys(v any) any {
+	switch vals.Kind(v) {
+	case "list":
+		var s []any
+		vals.Iterate(v, func(elem any) bool {
+			s = append(s, sortJSONKeys(elem))
+			return true
+		})
+		return s
+	case "map":
+		m := make(map[string]any)
+		vals.IterateKeys(v, func(k any) bool {
+			val, _ := vals.Index(v, k)
+			m[vals.ToString(k)] = sortJSONKeys(val)
+			return true
+		})
+		return m
+	default:
+		return v
+	}
+}
+
+type fromCSVOpts struct {
+	Delimiter string
+	Header    bool
+}
+
+func (o *fromCSVOpts) SetDefaultOptions() { o.Delimiter = "," }
+
+func parseCSVDelimiter(s string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || size != len(s) || r == '"' || r == '\r' || r == '\n' {
+		return 0, errs.BadValue{What: "delimiter",
+			Valid:  "a single character other than quote, CR and LF",
+			Actual: parse.Quote(s)}
+	}
+	return r, nil
+}
+
+func fromCSV(fm *Frame, opts fromCSVOpts) error {
+	delimiter, err := parseCSVDelimiter(opts.Delimiter)
+	if err != nil {
+		return err
+	}
+	r := csv.NewReader(fm.InputFile())
+	r.Comma = delimiter
+	out := fm.ValueOutput()
+	var header []string
+	for {
+		record, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if opts.Header && header == nil {
+			header = record
+			continue
+		}
+		var v any
+		if opts.Header {
+			m := vals.EmptyMap
+			for i, field := range record {
+				m = m.Assoc(header[i], field)
+			}
+			v = m
+		} else {
+			l := vals.EmptyList
+			for _, field := range record {
+				l = l.Conj(field)
+			}
+			v = l
+		}
+		err = out.Put(v)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type toCSVOpts struct {
+	Delimiter string
+	Columns   vals.List
+}
+
+func (o *toCSVOpts) SetDefaultOptions() { o.Delimiter = "," }
+
+func toCSV(fm *Frame, opts toCSVOpts, inputs Inputs) error {
+	delimiter, err := parseCSVDelimiter(opts.Delimiter)
+	if err != nil {
+		return err
+	}
+	var columns []any
+	if opts.Columns != nil {
+		columns = make([]any, 0, opts.Columns.Len())
+		for it := opts.Columns.Iterator(); it.HasElem(); it.Next() {
+			columns = append(columns, it.Elem())
+		}
+	}
+	w := csv.NewWriter(fm.ByteOutput())
+	w.Comma = delimiter
+	wroteHeader := false
+
+	var errOut error
+	inputs(func(v any) {
+		if errOut != nil {
+			return
+		}
+		var record []string
+		if vals.Kind(v) == "map" {
+			if columns == nil {
+				columns = sortedKeys(v)
+			}
+			if !wroteHeader {
+				errOut = w.Write(csvRecord(columns))
+				if errOut != nil {
+					return
+				}
+				wroteHeader = true
+			}
+			record = make([]string, len(columns))
+			for i, column := range columns {
+				if vals.HasKey(v, column) {
+					field, _ := vals.Index(v, column)
+					record[i] = csvField(field)
+				}
+			}
+		} else {
+			var fields []any
+			errOut = vals.Iterate(v, func(field any) bool {
+				fields = append(fields, field)
+				return true
+			})
+			if errOut != nil {
+				return
+			}
+			record = csvRecord(fields)
+		}
+		errOut = w.Write(record)
+	})
+	if errOut != nil {
+		return errOut
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func csvRecord(fields []any) []string {
+	record := make([]string, len(fields))
+	for i, field := range fields {
+		record[i] = csvField(field)
+	}
+	return record
+}
+
+func csvField(v any) string {
+	if v == nil {
+		return ""
+	}
+	return vals.ToString(v)
+}
+
+func fromYAML(fm *Frame) error {
+	dec := yaml.NewDecoder(fm.InputFile())
+	out := fm.ValueOutput()
+	for {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		converted, err := fromYAMLNode(&node)
+		if err != nil {
+			return err
+		}
+		err = out.Put(converted)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// The maximum number of nodes that can be expanded from aliases in one YAML
+// document, which guards against documents that nest aliases to expand to a
+// huge number of nodes, like the "billion laughs" attack. The limit is similar
+// to the one of yaml.v3's decoder.
+const maxYAMLAliasNodes = 1000000
+
+// Converts YAML nodes to Elvish values. Nodes are used instead of decoding
+// into Go values, since integers that don't fit in 64 bits would be decoded as
+// floating-point numbers.
+type yamlConverter struct {
+	// Anchored nodes that are being expanded from aliases.
+	expanding map[*yaml.Node]bool
+	// Number of nodes expanded from aliases so far.
+	aliasNodes int
+}
+
+func fromYAMLNode(node *yaml.Node) (any, error) {
+	c := &yamlConverter{expanding: make(map[*yaml.Node]bool)}
+	return c.convert(node)
+}
+
+// Marks the node an alias refers to as being expanded, rejecting cycles. The
+// returned function must be called after the expansion is done.
+func (c *yamlConverter) enterAlias(alias *yaml.Node) (func(), error) {
+	if c.expanding[alias.Alias] {
+		return nil, fmt.Errorf("line %d: alias *%s refers to itself", alias.Line, alias.Value)
+	}
+	c.expanding[alias.Alias] = true
+	return func() { delete(c.expanding, alias.Alias) }, nil
+}
+
+func (c *yamlConverter) convert(node *yaml.Node) (any, error) {
+	if len(c.expanding) > 0 {
+		c.aliasNodes++
+		if c.aliasNodes > maxYAMLAliasNodes {
+			return nil, fmt.Errorf("line %d: too many nodes expanded from aliases", node.Line)
+		}
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return c.convert(node.Content[0])
+	case yaml.AliasNode:
+		leave, err := c.enterAlias(node)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		return c.convert(node.Alias)
+	case yaml.SequenceNode:
+		l := vals.EmptyList
+		for _, elemNode := range node.Content {
+			elem, err := c.convert(elemNode)
+			if err != nil {
+				return nil, err
+			}
+			l = l.Conj(elem)
+		}
+		return l, nil
+	case yaml.MappingNode:
+		m := vals.EmptyMap
+		var merged []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valNode := node.Content[i], node.Content[i+1]
+			if keyNode.ShortTag() == "!!merge" {
+				merged = append(merged, valNode)
+				continue
+			}
+			k, err := c.convert(keyNode)
+			if err != nil {
+				return nil, err
+			}
+			v, err := c.convert(valNode)
+			if err != nil {
+				return nil, err
+			}
+			m = m.Assoc(k, v)
+		}
+		// Keys in the map itself take precedence over merged keys.
+		for _, mergedNode := range merged {
+			var err error
+			m, err = c.merge(m, mergedNode)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	default:
+		tag := node.ShortTag()
+		// Integers that don't fit in 64 bits are resolved as floats when they
+		// don't have an explicit tag.
+		if tag == "!!int" || (tag == "!!float" && node.Style&yaml.TaggedStyle == 0) {
+			// Try parsing as a big int first; this fails for floats and the
+			// YAML 1.1 syntaxes like 0b101, which are left to yaml.v3.
+			s := strings.ReplaceAll(node.Value, "_", "")
+			if z, ok := new(big.Int).SetString(s, 0); ok {
+				return vals.NormalizeBigInt(z), nil
+			}
+		}
+		// Timestamps without an explicit tag are kept as written.
+		if tag == "!!timestamp" && node.Style&yaml.TaggedStyle == 0 {
+			return node.Value, nil
+		}
+		var v any
+		err := node.Decode(&v)
+		if err != nil {
+			return nil, err
+		}
+		return fromGoData(v)
+	}
+}
+
+// Merges the keys in the value of a merge key that are not already in m.
+func (c *yamlConverter) merge(m vals.Map, mergedNode *yaml.Node) (vals.Map, error) {
+	if mergedNode.Kind == yaml.AliasNode {
+		leave, err := c.enterAlias(mergedNode)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		mergedNode = mergedNode.Alias
+	}
+	mergedMaps := []*yaml.Node{mergedNode}
+	if mergedNode.Kind == yaml.SequenceNode {
+		mergedMaps = mergedNode.Content
+	}
+	for _, mergedMap := range mergedMaps {
+		v, err := c.convert(mergedMap)
+		if err != nil {
+			return nil, err
+		}
+		mv, ok := v.(vals.Map)
+		if !ok {
+			return nil, fmt.Errorf("line %d: merged value is not a map", mergedMap.Line)
+		}
+		for it := mv.Iterator(); it.HasElem(); it.Next() {
+			k, v := it.Elem()
+			if _, ok := m.Index(k); !ok {
+				m = m.Assoc(k, v)
+			}
+		}
+	}
+	return m, nil
+}
+
+func fromTOML(fm *Frame) error {
+	var m map[string]any
+	_, err := toml.NewDecoder(fm.InputFile()).Decode(&m)
+	if err != nil {
+		return err
+	}
+	converted, err := fromGoData(m)
+	if err != nil {
+		return err
+	}
+	return fm.ValueOutput().Put(converted)
+}
+
+// Converts a value that results from decoding a YAML scalar or TOML to an
+// Elvish value.
+func fromGoData(v any) (any, error) {
+	switch v := v.(type) {
+	case nil, bool, string, int, float64:
+		return v, nil
+	case int64:
+		return vals.NormalizeBigInt(big.NewInt(v)), nil
+	case uint64:
+		return vals.NormalizeBigInt(new(big.Int).SetUint64(v)), nil
+	case time.Time:
+		return formatTime(v), nil
+	case []byte:
+		return string(v), nil
+	case []any:
+		return fromGoList(v)
+	case []map[string]any:
+		// Arrays of tables in TOML.
+		return fromGoList(v)
+	case map[string]any:
+		return fromGoMap(v)
+	default:
+		return nil, fmt.Errorf("unexpected data type: %T", v)
+	}
+}
+
+func fromGoList[T any](s []T) (any, error) {
+	l := vals.EmptyList
+	for _, elem := range s {
+		converted, err := fromGoData(elem)
+		if err != nil {
+			return nil, err
+		}
+		l = l.Conj(converted)
+	}
+	return l, nil
+}
+
+func fromGoMap(m map[string]any) (any, error) {
+	converted := vals.EmptyMap
+	for k, v := range m {
+		convertedVal, err := fromGoData(v)
+		if err != nil {
+			return nil, err
+		}
+		converted = converted.Assoc(k, convertedVal)
+	}
+	return converted, nil
+}
+
+// Formats a time decoded from YAML or TOML. TOML supports local dates, times
+// and date-times, which are decoded as times in locations with special names.
+func formatTime(t time.Time) string {
+	switch t.Location().String() {
+	case "date-local":
+		return t.Format(time.DateOnly)
+	case "time-local":
+		return t.Format("15:04:05.999999999")
+	case "datetime-local":
+		return t.Format("2006-01-02T15:04:05.999999999")
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
+
+func toYAML(fm *Frame, inputs Inputs) error {
+	enc := yaml.NewEncoder(fm.ByteOutput())
+	enc.SetIndent(2)
+	var errEncode error
+	inputs(func(v any) {
+		if errEncode != nil {
+			return
+		}
+		var node *yaml.Node
+		node, errEncode = toYAMLNode(v)
+		if errEncode != nil {
+			return
+		}
+		errEncode = enc.Encode(node)
+	})
+	if errEncode != nil {
+		return errEncode
+	}
+	return enc.Close()
+}
+
+// Converts an Elvish value to a YAML node. Nodes are used instead of Go values
+// to keep the tags of numbers that can't be represented by Go's builtin
+// types.
+func toYAMLNode(v any) (*yaml.Node, error) {
+	scalar := func(tag, value string) (*yaml.Node, error) {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}, nil
+	}
+	switch v := v.(type) {
+	case nil:
+		return scalar("!!null", "null")
+	case bool:
+		return scalar("!!bool", strconv.FormatBool(v))
+	case string:
+		return scalar("!!str", v)
+	case int:
+		return scalar("!!int", strconv.Itoa(v))
+	case *big.Int:
+		return scalar("!!int", v.String())
+	case *big.Rat:
+		f, _ := v.Float64()
+		return scalar("!!float", formatYAMLFloat(f))
+	case float64:
+		return scalar("!!float", formatYAMLFloat(v))
+	}
+	switch vals.Kind(v) {
+	case "list":
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		var errElem error
+		err := vals.Iterate(v, func(elem any) bool {
+			var elemNode *yaml.Node
+			elemNode, errElem = toYAMLNode(elem)
+			node.Content = append(node.Content, elemNode)
+			return errElem == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return node, errElem
+	case "map":
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range sortedKeys(v) {
+			val, _ := vals.Index(v, k)
+			keyNode, err := toYAMLNode(k)
+			if err != nil {
+				return nil, err
+			}
+			valNode, err := toYAMLNode(val)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, keyNode, valNode)
+		}
+		return node, nil
+	}
+	return nil, errs.BadValue{What: "value to encode as YAML",
+		Valid: "string, bool, number, list, map or $nil", Actual: vals.Kind(v)}
+}
+
+func formatYAMLFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return ".nan"
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		// Make sure that the number is not parsed as an integer.
+		s += ".0"
+	}
+	return s
+}
+
+var errTOMLNil = errors.New("$nil can't be encoded as TOML")
+
+func toTOML(fm *Frame, inputs Inputs) error {
+	enc := toml.NewEncoder(fm.ByteOutput())
+	var errEncode error
+	inputs(func(v any) {
+		if errEncode != nil {
+			return
+		}
+		if vals.Kind(v) != "map" {
+			errEncode = errs.BadValue{What: "value to encode as TOML",
+				Valid: "map", Actual: vals.Kind(v)}
+			return
+		}
+		var converted any
+		converted, errEncode = toTOMLValue(v)
+		if errEncode != nil {
+			return
+		}
+		errEncode = enc.Encode(converted)
+	})
+	return errEncode
+}
+
+// Converts an Elvish value to a Go value that can be encoded as TOML.
+func toTOMLValue(v any) (any, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, errTOMLNil
+	case bool, string, int, float64:
+		return v, nil
+	case *big.Int:
+		if !v.IsInt64() {
+			return nil, errs.OutOfRange{What: "integer to encode as TOML",
+				ValidLow:  strconv.FormatInt(math.MinInt64, 10),
+				ValidHigh: strconv.FormatInt(math.MaxInt64, 10), Actual: v.String()}
+		}
+		return v.Int64(), nil
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f, nil
+	}
+	switch vals.Kind(v) {
+	case "list":
+		var s []any
+		var errElem error
+		err := vals.Iterate(v, func(elem any) bool {
+			var converted any
+			converted, errElem = toTOMLValue(elem)
+			s = append(s, converted)
+			return errElem == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return s, errElem
+	case "map":
+		m := make(map[string]any)
+		var errKey error
+		err := vals.IterateKeys(v, func(k any) bool {
+			ks, ok := k.(string)
+			if !ok {
+				errKey = errs.BadValue{What: "key to encode as TOML",
+					Valid: "string", Actual: vals.ReprPlain(k)}
+				return false
+			}
+			val, _ := vals.Index(v, k)
+			m[ks], errKey = toTOMLValue(val)
+			return errKey == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return m, errKey
+	}
+	return nil, errs.BadValue{What: "value to encode as TOML",
+		Valid: "string, bool, number, list or map", Actual: vals.Kind(v)}
+}
+
+// Returns the keys of a map, sorted with [vals.CmpTotal] for a deterministic
+// output.
+func sortedKeys(m any) []any {
+	var keys []any
+	vals.IterateKeys(m, func(k any) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		return vals.CmpTotal(keys[i], keys[j]) == vals.CmpLess
+	})
+	return keys
+}
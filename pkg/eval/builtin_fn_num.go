@@ -90,7 +90,20 @@ func num(n vals.Num) vals.Num {
 	return n
 }
 
-func exactNum(n vals.Num) (vals.Num, error) {
+func exactNum(arg any) (vals.Num, error) {
+	if s, ok := arg.(string); ok {
+		if _, isFloat := vals.ParseNum(s).(float64); isFloat {
+			// Parse the decimal string directly, since converting it to a
+			// float64 first may lose precision.
+			if r, ok := new(big.Rat).SetString(s); ok {
+				return vals.NormalizeBigRat(r), nil
+			}
+		}
+	}
+	var n vals.Num
+	if err := vals.ScanToGo(arg, &n); err != nil {
+		return nil, err
+	}
 	if f, ok := n.(float64); ok {
 		r := new(big.Rat).SetFloat64(f)
 		if r == nil {
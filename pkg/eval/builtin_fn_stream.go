@@ -24,6 +24,13 @@ func init() {
 
 		"order": order,
 
+		// Grouping and aggregation
+		"group-by":  groupBy,
+		"count-by":  countBy,
+		"unique":    unique,
+		"zip":       zip,
+		"enumerate": enumerate,
+
 		// Iterations
 		"keep-if": keepIf,
 	})
@@ -124,12 +131,15 @@ func count(fm *Frame, args ...any) (int, error) {
 		if len := vals.Len(v); len >= 0 {
 			n = len
 		} else {
-			err := vals.Iterate(v, func(any) bool {
+			if !vals.CanIterate(v) {
+				return 0, fmt.Errorf("cannot get length of a %s", vals.Kind(v))
+			}
+			err := iterate(fm, v, func(any) bool {
 				n++
 				return true
 			})
 			if err != nil {
-				return 0, fmt.Errorf("cannot get length of a %s", vals.Kind(v))
+				return 0, err
 			}
 		}
 	default:
@@ -142,7 +152,7 @@ func count(fm *Frame, args ...any) (int, error) {
 
 type orderOptions struct {
 	Reverse  bool
-	Key      Callable
+	Key      any
 	Total    bool
 	LessThan Callable
 }
@@ -160,20 +170,16 @@ func order(fm *Frame, opts orderOptions, inputs Inputs) error {
 	var values, keys []any
 	inputs(func(v any) { values = append(values, v) })
 	if opts.Key != nil {
+		key, err := makeKeyFn(fm, "&key", opts.Key)
+		if err != nil {
+			return err
+		}
 		keys = make([]any, len(values))
 		for i, value := range values {
-			outputs, err := fm.CaptureOutput(func(fm *Frame) error {
-				return opts.Key.Call(fm, []any{value}, NoOpts)
-			})
+			keys[i], err = key(value)
 			if err != nil {
 				return err
-			} else if len(outputs) != 1 {
-				return errs.ArityMismatch{
-					What:     "number of outputs of the &key callback",
-					ValidLow: 1, ValidHigh: 1, Actual: len(outputs),
-				}
 			}
-			keys[i] = outputs[0]
 		}
 	}
 
@@ -265,6 +271,187 @@ func (s *slice) Swap(i, j int) {
 	}
 }
 
+// A function that computes the key of a value, used for sorting and grouping.
+type keyFn func(v any) (any, error)
+
+// Makes a keyFn from a key specification, which can be a callable, a list, or
+// any other value. A callable is called with the value and must output exactly
+// one value. A list computes a list of keys, one for each of its elements,
+// which are compared lexicographically. Any other value is used as an index
+// into the value, which is done without calling any Elvish code.
+func makeKeyFn(fm *Frame, what string, spec any) (keyFn, error) {
+	switch spec := spec.(type) {
+	case Callable:
+		return func(v any) (any, error) {
+			outputs, err := fm.CaptureOutput(func(fm *Frame) error {
+				return spec.Call(fm, []any{v}, NoOpts)
+			})
+			if err != nil {
+				return nil, err
+			} else if len(outputs) != 1 {
+				return nil, errs.ArityMismatch{
+					What:     "number of outputs of the " + what + " callback",
+					ValidLow: 1, ValidHigh: 1, Actual: len(outputs),
+				}
+			}
+			return outputs[0], nil
+		}, nil
+	case vals.List:
+		fns := make([]keyFn, 0, spec.Len())
+		for it := spec.Iterator(); it.HasElem(); it.Next() {
+			fn, err := makeKeyFn(fm, what, it.Elem())
+			if err != nil {
+				return nil, err
+			}
+			fns = append(fns, fn)
+		}
+		return func(v any) (any, error) {
+			keys := vals.EmptyList
+			for _, fn := range fns {
+				key, err := fn(v)
+				if err != nil {
+					return nil, err
+				}
+				keys = keys.Conj(key)
+			}
+			return keys, nil
+		}, nil
+	default:
+		return func(v any) (any, error) { return vals.Index(v, spec) }, nil
+	}
+}
+
+func groupBy(fm *Frame, key any, inputs Inputs) (vals.Map, error) {
+	keyOf, err := makeKeyFn(fm, "key", key)
+	if err != nil {
+		return nil, err
+	}
+	groups := vals.EmptyMap
+	inputs(func(v any) {
+		if err != nil {
+			return
+		}
+		var k any
+		k, err = keyOf(v)
+		if err != nil {
+			return
+		}
+		group := vals.EmptyList
+		if g, ok := groups.Index(k); ok {
+			group = g.(vals.List)
+		}
+		groups = groups.Assoc(k, group.Conj(v))
+	})
+	return groups, err
+}
+
+func countBy(fm *Frame, key any, inputs Inputs) (vals.Map, error) {
+	keyOf, err := makeKeyFn(fm, "key", key)
+	if err != nil {
+		return nil, err
+	}
+	counts := vals.EmptyMap
+	inputs(func(v any) {
+		if err != nil {
+			return
+		}
+		var k any
+		k, err = keyOf(v)
+		if err != nil {
+			return
+		}
+		n := 0
+		if c, ok := counts.Index(k); ok {
+			n = c.(int)
+		}
+		counts = counts.Assoc(k, n+1)
+	})
+	return counts, err
+}
+
+type uniqueOpts struct{ Key any }
+
+func (*uniqueOpts) SetDefaultOptions() {}
+
+func unique(fm *Frame, opts uniqueOpts, inputs Inputs) error {
+	keyOf := func(v any) (any, error) { return v, nil }
+	if opts.Key != nil {
+		var err error
+		keyOf, err = makeKeyFn(fm, "&key", opts.Key)
+		if err != nil {
+			return err
+		}
+	}
+	out := fm.ValueOutput()
+	// Only the keys of the map are used.
+	seen := vals.EmptyMap
+	var errOut error
+	inputs(func(v any) {
+		if errOut != nil {
+			return
+		}
+		var k any
+		k, errOut = keyOf(v)
+		if errOut != nil {
+			return
+		}
+		if _, ok := seen.Index(k); ok {
+			return
+		}
+		seen = seen.Assoc(k, nil)
+		errOut = out.Put(v)
+	})
+	return errOut
+}
+
+func zip(fm *Frame, iterables ...any) error {
+	// Collect the elements first, since an iterable may not support indexing.
+	lists := make([][]any, len(iterables))
+	n := -1
+	for i, iterable := range iterables {
+		err := iterate(fm, iterable, func(v any) bool {
+			lists[i] = append(lists[i], v)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		if n == -1 || len(lists[i]) < n {
+			n = len(lists[i])
+		}
+	}
+	out := fm.ValueOutput()
+	for j := 0; j < n; j++ {
+		tuple := vals.EmptyList
+		for _, list := range lists {
+			tuple = tuple.Conj(list[j])
+		}
+		err := out.Put(tuple)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type enumerateOpts struct{ Start int }
+
+func (*enumerateOpts) SetDefaultOptions() {}
+
+func enumerate(fm *Frame, opts enumerateOpts, inputs Inputs) error {
+	out := fm.ValueOutput()
+	i := opts.Start
+	var errOut error
+	inputs(func(v any) {
+		if errOut != nil {
+			return
+		}
+		errOut = out.Put(vals.MakeList(i, v))
+		i++
+	})
+	return errOut
+}
+
 func keepIf(fm *Frame, f Callable, inputs Inputs) error {
 	var err error
 	inputs(func(v any) {
@@ -0,0 +1,215 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/persistent/hash"
+)
+
+// Tasks.
+
+func init() {
+	addBuiltinFns(map[string]any{
+		"spawn":      spawn,
+		"await":      await,
+		"cancel":     cancelTasks,
+		"wait-any":   waitAny,
+		"task-group": taskGroupFn,
+	})
+}
+
+// ErrTaskCanceled is thrown when awaiting a task that has been canceled.
+var ErrTaskCanceled = errors.New("task canceled")
+
+// A task runs a function asynchronously, and keeps its outputs and exception
+// after it finishes.
+type task struct {
+	cancel context.CancelCauseFunc
+	// Closed when the function has finished.
+	done chan struct{}
+	// Only valid after done is closed.
+	values []any
+	exc    Exception
+}
+
+func (*task) Kind() string { return "task" }
+
+// Equal compares by address.
+func (t *task) Equal(rhs any) bool { return t == rhs }
+
+// Hash returns the hash of the address of the task.
+func (t *task) Hash() uint32 { return hash.Pointer(unsafe.Pointer(t)) }
+
+func (t *task) Repr(int) string { return fmt.Sprintf("<task %p>", t) }
+
+// A group of tasks started by spawn in the dynamic extent of task-group.
+type taskGroup struct {
+	// Cancels the Context of the function passed to task-group.
+	cancelFn context.CancelCauseFunc
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	tasks    []*task
+	canceled bool
+}
+
+func (g *taskGroup) add(t *task) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tasks = append(g.tasks, t)
+	g.wg.Add(1)
+	if g.canceled {
+		t.cancel(ErrTaskCanceled)
+	}
+}
+
+// Cancels the function passed to task-group and all the tasks in the group.
+func (g *taskGroup) cancel() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.canceled = true
+	g.cancelFn(ErrTaskCanceled)
+	for _, t := range g.tasks {
+		t.cancel(ErrTaskCanceled)
+	}
+}
+
+func spawn(fm *Frame, f Callable) *task {
+	// The Context of fm is canceled when the evaluation finishes, which should
+	// not cancel the task; only propagate cancellations from timeouts and
+	// enclosing tasks.
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(fm.ctx))
+	stopPropagating := context.AfterFunc(fm.ctx, func() {
+		if propagatesCancel(fm.ctx) {
+			cancel(context.Cause(fm.ctx))
+		}
+	})
+	t := &task{cancel: cancel, done: make(chan struct{})}
+	newFm := fm.Fork()
+	newFm.ctx = ctx
+	// The task may outlive the current pipeline, so it can't be part of the
+	// job of the pipeline.
+	newFm.job = nil
+	traceback := fm.traceback
+	g := fm.taskGroup
+	if g != nil {
+		g.add(t)
+	}
+	go func() {
+		values, err := newFm.CaptureOutput(func(fm *Frame) error {
+			return f.Call(fm, NoArgs, NoOpts)
+		})
+		if err != nil && context.Cause(ctx) == ErrTaskCanceled {
+			err = ErrTaskCanceled
+		}
+		t.values = values
+		if err != nil {
+			t.exc = toException(err, traceback)
+		}
+		close(t.done)
+		stopPropagating()
+		cancel(nil)
+		if g != nil {
+			if err != nil && err != ErrTaskCanceled {
+				// Cancel the rest of the group on the first failure.
+				g.cancel()
+			}
+			g.wg.Done()
+		}
+	}()
+	return t
+}
+
+func await(fm *Frame, tasks ...*task) error {
+	excs := make([]Exception, len(tasks))
+	out := fm.ValueOutput()
+	for i, t := range tasks {
+		select {
+		case <-t.done:
+		case <-fm.ctx.Done():
+			return ErrInterrupted
+		}
+		for _, v := range t.values {
+			err := out.Put(v)
+			if err != nil {
+				return err
+			}
+		}
+		excs[i] = t.exc
+	}
+	return MakePipelineError(excs)
+}
+
+func cancelTasks(tasks ...*task) {
+	for _, t := range tasks {
+		t.cancel(ErrTaskCanceled)
+	}
+}
+
+func waitAny(fm *Frame, tasks ...*task) (*task, error) {
+	if len(tasks) == 0 {
+		return nil, errs.ArityMismatch{What: "arguments", ValidLow: 1, ValidHigh: -1, Actual: 0}
+	}
+	first := make(chan *task, len(tasks))
+	stop := make(chan struct{})
+	defer close(stop)
+	for _, t := range tasks {
+		go func() {
+			select {
+			case <-t.done:
+				first <- t
+			case <-stop:
+			}
+		}()
+	}
+	select {
+	case t := <-first:
+		return t, nil
+	case <-fm.ctx.Done():
+		return nil, ErrInterrupted
+	}
+}
+
+func taskGroupFn(fm *Frame, f Callable) error {
+	ctx, cancel := context.WithCancelCause(fm.ctx)
+	defer cancel(nil)
+	g := &taskGroup{cancelFn: cancel}
+	// Tasks don't inherit all cancellations of the Context of the frame that
+	// spawns them, so cancel them explicitly.
+	defer context.AfterFunc(fm.ctx, g.cancel)()
+	newFm := fm.Fork()
+	newFm.ctx = ctx
+	newFm.taskGroup = g
+
+	var excs []Exception
+	err := f.Call(newFm, NoArgs, NoOpts)
+	if err != nil {
+		if context.Cause(ctx) != ErrTaskCanceled {
+			// Only keep the exception if it is not caused by a task failing
+			// and canceling the group.
+			excs = append(excs, toException(err, fm.traceback))
+		}
+		g.cancel()
+	}
+	g.wg.Wait()
+	for _, t := range g.tasks {
+		if t.exc != nil && t.exc.Reason() != ErrTaskCanceled {
+			excs = append(excs, t.exc)
+		}
+	}
+	if len(excs) == 0 && fm.ctx.Err() != nil {
+		return ErrInterrupted
+	}
+	return MakePipelineError(excs)
+}
+
+func toException(err error, traceback *StackTrace) Exception {
+	if exc, ok := err.(Exception); ok {
+		return exc
+	}
+	return &exception{err, traceback}
+}
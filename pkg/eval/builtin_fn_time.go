@@ -1,6 +1,7 @@
 package eval
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"math/big"
@@ -14,9 +15,11 @@ import (
 
 func init() {
 	addBuiltinFns(map[string]any{
-		"sleep":     sleep,
-		"time":      timeCmd,
-		"benchmark": benchmark,
+		"sleep":         sleep,
+		"time":          timeCmd,
+		"benchmark":     benchmark,
+		"with-timeout":  withTimeout,
+		"with-deadline": withDeadline,
 	})
 }
 
@@ -29,24 +32,10 @@ var (
 )
 
 func sleep(fm *Frame, duration any) error {
-	var f float64
-	var d time.Duration
-
-	if err := vals.ScanToGo(duration, &f); err == nil {
-		d = time.Duration(f * float64(time.Second))
-	} else {
-		// See if it is a duration string rather than a simple number.
-		switch duration := duration.(type) {
-		case string:
-			d, err = time.ParseDuration(duration)
-			if err != nil {
-				return ErrInvalidSleepDuration
-			}
-		default:
-			return ErrInvalidSleepDuration
-		}
+	d, ok := scanDuration(duration)
+	if !ok {
+		return ErrInvalidSleepDuration
 	}
-
 	if d < 0 {
 		return ErrNegativeSleepDuration
 	}
@@ -59,6 +48,68 @@ func sleep(fm *Frame, duration any) error {
 	}
 }
 
+// Scans a duration, which is either a number of seconds or a string accepted
+// by [time.ParseDuration].
+func scanDuration(v any) (time.Duration, bool) {
+	var f float64
+	if err := vals.ScanToGo(v, &f); err == nil {
+		return time.Duration(f * float64(time.Second)), true
+	}
+	// See if it is a duration string rather than a simple number.
+	if s, ok := v.(string); ok {
+		d, err := time.ParseDuration(s)
+		return d, err == nil
+	}
+	return 0, false
+}
+
+func withTimeout(fm *Frame, timeout any, f Callable) error {
+	d, ok := scanDuration(timeout)
+	if !ok {
+		return errs.BadValue{What: "timeout",
+			Valid: "number or duration string", Actual: vals.ReprPlain(timeout)}
+	}
+	if d < 0 {
+		return errs.BadValue{What: "timeout",
+			Valid: "non-negative duration", Actual: vals.ReprPlain(timeout)}
+	}
+	return callWithDeadline(fm, time.Now().Add(d), f)
+}
+
+func withDeadline(fm *Frame, deadline any, f Callable) error {
+	var t time.Time
+	var sec float64
+	if err := vals.ScanToGo(deadline, &sec); err == nil {
+		t = time.Unix(0, int64(sec*float64(time.Second)))
+	} else if s, ok := deadline.(string); ok {
+		t, err = time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return errs.BadValue{What: "deadline",
+				Valid: "number or RFC 3339 time string", Actual: parse.Quote(s)}
+		}
+	} else {
+		return errs.BadValue{What: "deadline",
+			Valid: "number or RFC 3339 time string", Actual: vals.ReprPlain(deadline)}
+	}
+	return callWithDeadline(fm, t, f)
+}
+
+// Calls f with a Context that is canceled at the deadline. If f throws an
+// exception after the deadline has passed, it is replaced with a [Timeout].
+func callWithDeadline(fm *Frame, deadline time.Time, f Callable) error {
+	ctx, cancel := context.WithDeadline(fm.ctx, deadline)
+	defer cancel()
+	newFm := fm.Fork()
+	newFm.ctx = ctx
+	err := f.Call(newFm, NoArgs, NoOpts)
+	// If the Context of fm is also done, it is up to the code that created it
+	// to handle the cancellation.
+	if err != nil && ctx.Err() != nil && fm.ctx.Err() == nil {
+		return Timeout{deadline}
+	}
+	return err
+}
+
 type timeOpt struct{ OnEnd Callable }
 
 func (o *timeOpt) SetDefaultOptions() {}
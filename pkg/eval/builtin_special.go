@@ -774,7 +774,7 @@ func (op *forOp) exec(fm *Frame) Exception {
 
 	iterated := false
 	var errElement error
-	errIterate := vals.Iterate(iterable, func(v any) bool {
+	errIterate := iterate(fm, iterable, func(v any) bool {
 		iterated = true
 		err := variable.Set(v)
 		if err != nil {
@@ -6,6 +6,7 @@ import (
 	"os"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"src.elv.sh/pkg/diag"
 	"src.elv.sh/pkg/eval/errs"
@@ -87,11 +88,25 @@ func (op *pipelineOp) exec(fm *Frame) Exception {
 		return fm.errorp(op, ErrInterrupted)
 	}
 
+	var j *job
+	var bgJob BgJob
+	// Context for interrupting a job in the foreground.
+	var intCtx context.Context
 	if op.bg {
 		fm = fm.Fork()
 		fm.ctx = context.Background()
 		fm.background = true
 		fm.Evaler.addNumBgJobs(1)
+		j = newJob(fm.Evaler, op.source, fm.jobControl, false)
+		fm.Evaler.jobs.add(j)
+		fm.job = j
+		bgJob = BgJob{ID: j.getID(), Source: op.source, Start: time.Now()}
+		runBgJobHooks(fm.Evaler.BeforeBgJob, bgJob)
+	} else if fm.jobControl && fm.job == nil {
+		fm = fm.Fork()
+		j = newJob(fm.Evaler, op.source, true, true)
+		fm.job = j
+		intCtx, fm.ctx = fm.ctx, j.ctx
 	}
 
 	nforms := len(op.forms)
@@ -151,33 +166,35 @@ func (op *pipelineOp) exec(fm *Frame) Exception {
 			}
 			wg.Done()
 		}
-		if i == nforms-1 && !op.bg {
+		if i == nforms-1 && j == nil {
 			f(form, fops, &excs[i])
 		} else {
 			go f(form, fops, &excs[i])
 		}
 	}
 
-	if op.bg {
-		// Background job, wait for form termination asynchronously.
-		go func() {
-			wg.Wait()
+	if j == nil {
+		wg.Wait()
+		return fm.errorp(op, MakePipelineError(excs))
+	}
+	// Wait for form termination asynchronously, so that a job in the
+	// foreground can stop waiting when it gets stopped.
+	go func() {
+		wg.Wait()
+		err := fm.errorp(op, MakePipelineError(excs))
+		if op.bg {
 			fm.Evaler.addNumBgJobs(-1)
-			if notify := fm.Evaler.BgJobNotify; notify != nil {
-				msg := "job " + op.source + " finished"
-				err := MakePipelineError(excs)
-				if err != nil {
-					msg += ", errors = " + err.Error()
-				}
-				if fm.Evaler.getNotifyBgJobSuccess() || err != nil {
-					notify(msg)
-				}
-			}
-		}()
+		}
+		j.finish(err)
+		if op.bg {
+			bgJob.End, bgJob.Err = time.Now(), err
+			runBgJobHooks(fm.Evaler.AfterBgJob, bgJob)
+		}
+	}()
+	if op.bg {
 		return nil
 	}
-	wg.Wait()
-	return fm.errorp(op, MakePipelineError(excs))
+	return fm.errorp(op, j.waitInFg(intCtx))
 }
 
 func isReaderGone(exc Exception) bool {
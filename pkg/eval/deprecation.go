@@ -27,3 +27,21 @@ func (r *deprecationRegistry) register(dep deprecation) bool {
 	r.registered[dep] = struct{}{}
 	return true
 }
+
+// DeprecatedBuiltin returns the deprecation message for a builtin, along with
+// the minimum deprecation level at which the message should be shown. The name
+// has a [FnSuffix] for functions. The last return value is false if the builtin
+// is not deprecated.
+//
+// This function doesn't check whether the name actually refers to a builtin.
+func DeprecatedBuiltin(name string) (msg string, minLevel int, ok bool) {
+	switch name {
+	// We don't have any deprecated builtins targeted for 0.22 yet, but keep
+	// this code here so that the code doesn't get stale. Callers only call
+	// this function for symbols that actually resolve to builtins, so having a
+	// fake one here is harmless.
+	case "foo~":
+		return `the "foo" command is deprecated; use "bar" instead`, 22, true
+	}
+	return "", 0, false
+}
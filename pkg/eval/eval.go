@@ -55,6 +55,9 @@ type Evaler struct {
 	// Callback to notify the success or failure of background jobs. Must not be
 	// mutated once the Evaler is used to evaluate any code.
 	BgJobNotify func(string)
+	// Background job hooks, exposed indirectly as $before-bg-job and
+	// $after-bg-job.
+	BeforeBgJob, AfterBgJob []func(BgJob)
 	// Path to the rc file, and path to the rc file actually evaluated. These
 	// are not used by the Evaler itself right now; they are here so that they
 	// can be exposed to the runtime: module.
@@ -86,6 +89,12 @@ type Evaler struct {
 	notifyBgJobSuccess bool
 	// The current number of background jobs, exposed as $num-bg-jobs.
 	numBgJobs int
+
+	// Jobs that can be manipulated with the jobs, fg, bg and disown builtins.
+	jobs jobTable
+	// Used to manipulate process groups of jobs when job control is enabled.
+	// Nil if the JobController can't be created.
+	jobController JobController
 }
 
 // NewEvaler creates a new Evaler.
@@ -96,6 +105,8 @@ func NewEvaler() *Evaler {
 	beforeExitHookElvish := newListVar(vals.EmptyList)
 	beforeChdirElvish := newListVar(vals.EmptyList)
 	afterChdirElvish := newListVar(vals.EmptyList)
+	beforeBgJobElvish := newListVar(vals.EmptyList)
+	afterBgJobElvish := newListVar(vals.EmptyList)
 
 	ev := &Evaler{
 		global:  new(Ns),
@@ -111,6 +122,9 @@ func NewEvaler() *Evaler {
 		numBgJobs:          0,
 		Args:               vals.EmptyList,
 	}
+	if jobController, err := NewJobController(); err == nil {
+		ev.jobController = jobController
+	}
 
 	ev.PreExitHooks = []func(){func() {
 		CallHook(ev, nil, "before-exit", beforeExitHookElvish.Get().(vals.List))
@@ -121,12 +135,20 @@ func NewEvaler() *Evaler {
 	ev.AfterChdir = []func(string){func(path string) {
 		CallHook(ev, nil, "after-chdir", afterChdirElvish.Get().(vals.List), path)
 	}}
+	ev.BeforeBgJob = []func(BgJob){func(r BgJob) {
+		CallHook(ev, nil, "before-bg-job", beforeBgJobElvish.Get().(vals.List), r.Map())
+	}}
+	ev.AfterBgJob = []func(BgJob){func(r BgJob) {
+		CallHook(ev, nil, "after-bg-job", afterBgJobElvish.Get().(vals.List), r.Map())
+	}}
 
 	ev.ExtendBuiltin(BuildNs().
 		AddVar("pwd", NewPwdVar(ev)).
 		AddVar("before-exit", beforeExitHookElvish).
 		AddVar("before-chdir", beforeChdirElvish).
 		AddVar("after-chdir", afterChdirElvish).
+		AddVar("before-bg-job", beforeBgJobElvish).
+		AddVar("after-bg-job", afterBgJobElvish).
 		AddVar("value-out-indicator",
 			vars.FromPtrWithMutex(&ev.valuePrefix, &ev.mu)).
 		AddVar("notify-bg-job-success",
@@ -280,6 +302,11 @@ type EvalCfg struct {
 	// Whether the Eval method should try to put the Elvish in the foreground
 	// after the code is executed.
 	PutInFg bool
+	// Whether to enable job control, which puts each foreground pipeline in
+	// its own process group and gives it the terminal, so that it can be
+	// stopped with Ctrl-Z and later resumed with fg or bg. Has no effect on
+	// Windows.
+	JobControl bool
 	// If not nil, used the given global namespace, instead of Evaler's own.
 	Global *Ns
 }
@@ -382,7 +409,8 @@ func (ev *Evaler) prepareFrame(src parse.Source, cfg EvalCfg) (*Frame, func()) {
 
 	ports := fillDefaultDummyPorts(cfg.Ports)
 
-	fm := &Frame{ev, intCtx, ports, nil, false, src, cfg.Global, new(Ns), nil}
+	jobControl := cfg.JobControl && jobControlSupported && ev.jobController != nil
+	fm := &Frame{ev, intCtx, ports, nil, false, nil, jobControl, nil, src, cfg.Global, new(Ns), nil}
 	return fm, func() {
 		if cfg.PutInFg {
 			err := putSelfInFg()
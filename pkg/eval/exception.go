@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"src.elv.sh/pkg/diag"
@@ -254,6 +255,23 @@ type flowFields struct{ f Flow }
 func (f flowFields) Type() string { return "flow" }
 func (f flowFields) Name() string { return f.f.Error() }
 
+// Timeout is thrown by with-timeout and with-deadline when the function
+// doesn't finish before the deadline.
+type Timeout struct{ Deadline time.Time }
+
+var _ vals.PseudoMap = Timeout{}
+
+func (Timeout) Error() string { return "timed out" }
+
+func (t Timeout) Kind() string           { return "timeout-error" }
+func (t Timeout) Fields() vals.MethodMap { return timeoutFields{t} }
+
+type timeoutFields struct{ t Timeout }
+
+func (timeoutFields) Type() string { return "timeout" }
+
+func (f timeoutFields) Deadline() float64 { return vals.UnixSeconds(f.t.Deadline) }
+
 // ExternalCmdExit contains the exit status of external commands.
 type ExternalCmdExit struct {
 	syscall.WaitStatus
@@ -342,3 +360,22 @@ func (f exitFieldsStopped) TrapCause() int       { return f.e.TrapCause() }
 type exitFieldsUnknown struct{ exitFieldsCommon }
 
 func (exitFieldsUnknown) Type() string { return "external-cmd/unknown" }
+
+// ExitStatus returns the exit status corresponding to an error returned from
+// evaluating code: 0 if err is nil, the exit status of the external command
+// (or 128 plus the signal number if it was killed by a signal) if err is caused
+// by an [ExternalCmdExit], and 1 otherwise.
+func ExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exit, ok := Reason(err).(ExternalCmdExit); ok {
+		switch {
+		case exit.Exited():
+			return exit.ExitStatus()
+		case exit.Signaled():
+			return 128 + int(exit.Signal())
+		}
+	}
+	return 1
+}
@@ -1,13 +1,13 @@
 package eval
 
 import (
+	"context"
 	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
-	"syscall"
 
 	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/eval/vals"
@@ -105,13 +105,35 @@ func (e externalCmd) Call(fm *Frame, argVals []any, opts map[string]any) error {
 
 	args[0] = path
 
-	sys := makeSysProcAttr(fm.background)
-	proc, err := os.StartProcess(path, args, &os.ProcAttr{Files: files, Sys: sys})
+	var proc *os.Process
+	if fm.job != nil {
+		proc, err = fm.job.startProcess(path, args, files, fm.background)
+	} else {
+		sys := makeSysProcAttr(fm.background)
+		proc, err = os.StartProcess(path, args, &os.ProcAttr{Files: files, Sys: sys})
+	}
 	if err != nil {
 		return err
 	}
-
-	state, err := proc.Wait()
+	// Kill the process when the deadline set by with-timeout or with-deadline
+	// is exceeded, or when the task running it is canceled.
+	stop := context.AfterFunc(fm.ctx, func() {
+		if propagatesCancel(fm.ctx) {
+			proc.Kill()
+		}
+	})
+	defer stop()
+
+	// Saved since waitProcess may release proc, which resets proc.Pid.
+	pid := proc.Pid
+	ws, err := waitProcess(proc, fm.job)
+	if fm.job != nil {
+		fm.job.processExited(pid)
+		// The job may still be stopped, for example if the process was killed
+		// while stopped; don't run the code following it until the job is
+		// resumed.
+		fm.job.waitUntilRunning()
+	}
 	if err != nil {
 		// This should be a can't happen situation. Nonetheless, treat it as a
 		// soft error rather than panicking since the Go documentation is not
@@ -119,12 +141,11 @@ func (e externalCmd) Call(fm *Frame, argVals []any, opts map[string]any) error {
 		// calling `Wait` twice on a particular process object.
 		return err
 	}
-	ws := state.Sys().(syscall.WaitStatus)
 	if ws.Signaled() && isSIGPIPE(ws.Signal()) {
 		readerGone := fm.ports[1].readerGone
 		if readerGone != nil && readerGone.Load() {
 			return errs.ReaderGone{}
 		}
 	}
-	return NewExternalCmdExit(e.Name, state.Sys().(syscall.WaitStatus), proc.Pid)
+	return NewExternalCmdExit(e.Name, ws, pid)
 }
@@ -35,6 +35,14 @@ type Frame struct {
 	ports      []*Port
 	traceback  *StackTrace
 	background bool
+	// The job that the current pipeline belongs to, or nil if the current
+	// pipeline is not a job.
+	job *job
+	// Whether foreground pipelines should become jobs with job control.
+	jobControl bool
+	// The task group that tasks spawned in this frame belong to, or nil if
+	// not in the dynamic extent of a task-group call.
+	taskGroup *taskGroup
 
 	// The following fields are only relevant when running Elvish code (as
 	// opposed to a builtin function or external command).
@@ -66,7 +74,8 @@ func (fm *Frame) PrepareEval(src parse.Source, r diag.Ranger, ns *Ns) (*Ns, func
 		traceback = fm.addTraceback(r)
 	}
 	newFm := &Frame{
-		fm.Evaler, fm.ctx, fm.ports, traceback, fm.background, src, local, new(Ns), nil,
+		fm.Evaler, fm.ctx, fm.ports, traceback, fm.background, fm.job, fm.jobControl,
+		fm.taskGroup, src, local, new(Ns), nil,
 	}
 	op, _, err := compile(fm.Evaler.Builtin().static(), local.static(), nil, tree, fm.ErrorFile())
 	if err != nil {
@@ -227,6 +227,8 @@ func (b *goFn) Call(f *Frame, args []any, opts map[string]any) error {
 		in = append(in, ptr.Elem())
 	}
 
+	// Set when iterating an iterable argument is interrupted.
+	var errIterate error
 	if b.inputs {
 		var inputs Inputs
 		if len(args) == len(b.normalArgs) {
@@ -237,10 +239,11 @@ func (b *goFn) Call(f *Frame, args []any, opts map[string]any) error {
 			if !vals.CanIterate(iterable) {
 				return fmt.Errorf("%s cannot be iterated", vals.Kind(iterable))
 			}
-			inputs = func(f func(any)) {
-				// CanIterate(iterable) is true
-				_ = vals.Iterate(iterable, func(v any) bool {
-					f(v)
+			inputs = func(put func(any)) {
+				// CanIterate(iterable) is true, so the only possible error is
+				// from receiving from a channel being interrupted.
+				errIterate = iterate(f, iterable, func(v any) bool {
+					put(v)
 					return true
 				})
 			}
@@ -249,6 +252,9 @@ func (b *goFn) Call(f *Frame, args []any, opts map[string]any) error {
 	}
 
 	rets := reflect.ValueOf(b.impl).Call(in)
+	if errIterate != nil {
+		return errIterate
+	}
 
 	if len(rets) > 0 && rets[len(rets)-1].Type() == errorType {
 		err := rets[len(rets)-1].Interface()
@@ -33,3 +33,13 @@ func ListenInterrupts() (context.Context, func()) {
 		cancel()
 	}
 }
+
+// Reports whether the cancellation of ctx should be propagated to the external
+// commands and tasks started from it. This is the case when ctx is canceled
+// because of with-timeout, with-deadline, cancel or task-group, but not when
+// it is canceled because of interrupts (external commands will have received
+// the same signal) or because the evaluation has finished.
+func propagatesCancel(ctx context.Context) bool {
+	cause := context.Cause(ctx)
+	return errors.Is(cause, context.DeadlineExceeded) || cause == ErrTaskCanceled
+}
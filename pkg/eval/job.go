@@ -0,0 +1,448 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/sys"
+)
+
+// Job control.
+
+// ErrJobRunning is thrown when bg is called on a job that is not stopped.
+var ErrJobRunning = errors.New("job is already running")
+
+type jobState int
+
+const (
+	jobRunning jobState = iota
+	jobStopped
+)
+
+func (s jobState) String() string {
+	if s == jobStopped {
+		return "stopped"
+	}
+	return "running"
+}
+
+// A job is a pipeline tracked by the Evaler.
+//
+// Background pipelines are always jobs, and are added to the job table when
+// they are started. When job control is enabled, foreground pipelines are also
+// jobs, and are added to the job table when they get stopped.
+type job struct {
+	ev     *Evaler
+	source string
+	// Whether processes of the job are put in their own process group, which
+	// is given the terminal when the job is in the foreground. This is only
+	// true when job control is enabled and supported.
+	control bool
+	// Closed when all the forms of the pipeline have finished.
+	done chan struct{}
+	// Written to without blocking when a process of the job is stopped while
+	// the job is in the foreground.
+	stopped chan struct{}
+	// Context for the Elvish code of a job that is started in the foreground
+	// with job control, and the function to cancel it. The code keeps running
+	// after the job is moved to the background, so it can't use the context of
+	// the evaluation that started it, which is canceled when the evaluation
+	// finishes. Only interrupts received while the job is waited for in the
+	// foreground are forwarded to it.
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu sync.Mutex
+	// ID in the job table, or 0 if the job is not in the job table.
+	id       int
+	state    jobState
+	fg       bool
+	disowned bool
+	finished bool
+	// Error of the pipeline; only valid when finished is true.
+	err error
+	// Closed when the job is running; replaced by an open channel when the job
+	// gets stopped.
+	running chan struct{}
+	// ID of the process group of the job from the JobController, or nil if
+	// there are no running processes in a process group.
+	jobID JobID
+	// PIDs of running processes.
+	pids []int
+}
+
+func newJob(ev *Evaler, source string, control, fg bool) *job {
+	running := make(chan struct{})
+	close(running)
+	j := &job{ev: ev, source: source, control: control, fg: fg,
+		done: make(chan struct{}), stopped: make(chan struct{}, 1),
+		running: running}
+	if fg {
+		j.ctx, j.cancel = context.WithCancelCause(context.Background())
+	}
+	return j
+}
+
+// Blocks while the job is stopped. This is called after an external command
+// of the job exits, so that the Elvish code following it doesn't run until the
+// job is resumed with fg or bg.
+func (j *job) waitUntilRunning() {
+	j.mu.Lock()
+	running := j.running
+	j.mu.Unlock()
+	<-running
+}
+
+// Starts an external command as part of the job.
+func (j *job) startProcess(path string, args []string, files []*os.File, bg bool) (*os.Process, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	attr := &os.ProcAttr{Files: files}
+	var proc *os.Process
+	var err error
+	if j.control {
+		attr.Sys = jobSysProcAttr(j.jobID)
+		sys.WithSIGTSTPCaught(func() {
+			proc, err = os.StartProcess(path, args, attr)
+		})
+	} else {
+		attr.Sys = makeSysProcAttr(bg)
+		proc, err = os.StartProcess(path, args, attr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	j.pids = append(j.pids, proc.Pid)
+	if j.control {
+		ctl := j.ev.jobController
+		newGroup := j.jobID == nil
+		if newGroup {
+			j.jobID, _ = ctl.CreateJob()
+		}
+		// The process is already running at this point; failing to track it
+		// only affects job control, so the errors are ignored.
+		ctl.AddProcess(j.jobID, proc.Pid)
+		if newGroup && j.fg {
+			// Don't use BringToForeground, which also sends SIGCONT.
+			giveTerminal(j.jobID)
+		}
+	}
+	return proc, nil
+}
+
+// Records that a process of the job has exited.
+func (j *job) processExited(pid int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i, p := range j.pids {
+		if p == pid {
+			j.pids = append(j.pids[:i], j.pids[i+1:]...)
+			break
+		}
+	}
+	if len(j.pids) == 0 && j.jobID != nil {
+		// The process group ceases to exist when all its processes have
+		// exited; the next process started will need a new one.
+		j.ev.jobController.ReleaseJob(j.jobID)
+		j.jobID = nil
+	}
+}
+
+// Records that a process of the job has been stopped.
+func (j *job) processStopped() {
+	j.mu.Lock()
+	if j.state == jobStopped {
+		j.mu.Unlock()
+		return
+	}
+	j.state = jobStopped
+	j.running = make(chan struct{})
+	fg, id := j.fg, j.id
+	j.mu.Unlock()
+	if fg {
+		select {
+		case j.stopped <- struct{}{}:
+		default:
+		}
+	} else if id != 0 {
+		j.ev.notifyBgJob("job " + j.source + " stopped, id = " + strconv.Itoa(id))
+	}
+}
+
+func (j *job) inFg() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.fg
+}
+
+// Called when all the forms of the pipeline have finished.
+func (j *job) finish(err error) {
+	j.mu.Lock()
+	j.finished = true
+	j.err = err
+	fg, id, disowned := j.fg, j.id, j.disowned
+	j.mu.Unlock()
+	close(j.done)
+	if fg || disowned {
+		// Jobs in the foreground are taken care of by waitInFg.
+		return
+	}
+	if id != 0 {
+		j.ev.jobs.remove(j)
+	}
+	msg := "job " + j.source + " finished"
+	if err != nil {
+		msg += ", errors = " + err.Error()
+	}
+	if j.ev.getNotifyBgJobSuccess() || err != nil {
+		j.ev.notifyBgJob(msg)
+	}
+}
+
+// Waits for a job in the foreground to either finish or get stopped. In the
+// latter case, the job is moved to the background and added to the job table,
+// and nil is returned. The job is interrupted if ctx is canceled while waiting.
+func (j *job) waitInFg(ctx context.Context) error {
+	if j.cancel != nil {
+		stop := context.AfterFunc(ctx, func() { j.cancel(context.Cause(ctx)) })
+		defer stop()
+	}
+	select {
+	case <-j.done:
+	case <-j.stopped:
+		j.mu.Lock()
+		if !j.finished {
+			j.fg = false
+			added := j.id == 0
+			j.mu.Unlock()
+			if added {
+				j.ev.jobs.add(j)
+			}
+			j.releaseTerminal()
+			j.ev.notifyBgJob("job " + j.source + " stopped, id = " + strconv.Itoa(j.getID()))
+			return nil
+		}
+		// The job has finished after it got stopped, and finish has left it
+		// to us to clean up.
+		j.mu.Unlock()
+	}
+	if j.getID() != 0 {
+		j.ev.jobs.remove(j)
+	}
+	j.releaseTerminal()
+	return j.err
+}
+
+// Puts the job in the foreground, and waits for it like waitInFg.
+func (j *job) putInFg(ctx context.Context) error {
+	// Discard any stale stop notification.
+	select {
+	case <-j.stopped:
+	default:
+	}
+	j.mu.Lock()
+	if j.finished {
+		// The job has just finished, and is being removed from the job table.
+		j.mu.Unlock()
+		return ErrJobNotFound
+	}
+	j.fg = true
+	j.resumeLocked()
+	jobID := j.jobID
+	j.mu.Unlock()
+	if jobID != nil {
+		err := j.ev.jobController.BringToForeground(jobID)
+		if err != nil {
+			j.mu.Lock()
+			j.fg = false
+			j.mu.Unlock()
+			return err
+		}
+	}
+	return j.waitInFg(ctx)
+}
+
+// Resumes a stopped job in the background.
+func (j *job) resumeInBg() error {
+	j.mu.Lock()
+	if j.state != jobStopped {
+		j.mu.Unlock()
+		return ErrJobRunning
+	}
+	j.resumeLocked()
+	jobID := j.jobID
+	j.mu.Unlock()
+	if jobID != nil {
+		return j.ev.jobController.SendToBackground(jobID)
+	}
+	return nil
+}
+
+// Marks the job as running, unblocking waitUntilRunning. This function assumes
+// the mutex is held.
+func (j *job) resumeLocked() {
+	if j.state == jobStopped {
+		j.state = jobRunning
+		close(j.running)
+	}
+}
+
+// Removes the job from the job table, without affecting its execution.
+func (j *job) disown() {
+	j.mu.Lock()
+	j.disowned = true
+	j.mu.Unlock()
+	j.ev.jobs.remove(j)
+}
+
+func (j *job) releaseTerminal() {
+	if j.control {
+		putSelfInFg()
+	}
+}
+
+func (j *job) getID() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.id
+}
+
+// A snapshot of the information about a job, for the jobs builtin.
+type jobInfo struct {
+	id     int
+	source string
+	state  jobState
+	pids   []int
+}
+
+func (j *job) info() jobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return jobInfo{j.id, j.source, j.state, append([]int(nil), j.pids...)}
+}
+
+// The job table, which contains background jobs and stopped jobs.
+type jobTable struct {
+	mu   sync.Mutex
+	jobs map[int]*job
+}
+
+// Adds a job to the table, assigning it the smallest unused ID.
+func (t *jobTable) add(j *job) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.jobs == nil {
+		t.jobs = make(map[int]*job)
+	}
+	id := 1
+	for t.jobs[id] != nil {
+		id++
+	}
+	t.jobs[id] = j
+	j.mu.Lock()
+	j.id = id
+	j.mu.Unlock()
+}
+
+func (t *jobTable) remove(j *job) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.id != 0 && t.jobs[j.id] == j {
+		delete(t.jobs, j.id)
+	}
+	j.id = 0
+}
+
+// Returns the job with the given ID, or the job with the largest ID if id is
+// 0.
+func (t *jobTable) get(id int) (*job, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if id == 0 {
+		var current *job
+		for i, j := range t.jobs {
+			if current == nil || i > id {
+				current, id = j, i
+			}
+		}
+		if current != nil {
+			return current, nil
+		}
+	} else if j, ok := t.jobs[id]; ok {
+		return j, nil
+	}
+	return nil, ErrJobNotFound
+}
+
+// Returns all the jobs, sorted by their IDs.
+func (t *jobTable) all() []*job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ids := make([]int, 0, len(t.jobs))
+	for id := range t.jobs {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	jobs := make([]*job, len(ids))
+	for i, id := range ids {
+		jobs[i] = t.jobs[id]
+	}
+	return jobs
+}
+
+func (ev *Evaler) notifyBgJob(msg string) {
+	if notify := ev.BgJobNotify; notify != nil {
+		notify(msg)
+	}
+}
+
+// BgJob contains information about a background job, passed to the
+// [Evaler.BeforeBgJob] and [Evaler.AfterBgJob] hooks.
+type BgJob struct {
+	// ID of the job in the job table when it was started.
+	ID int
+	// Source code of the pipeline.
+	Source string
+	Start  time.Time
+	// The following fields are only set for AfterBgJob hooks.
+	End time.Time
+	// Error of the pipeline; nil if it succeeded.
+	Err error
+}
+
+// Map converts the BgJob to a map for Elvish code. The start and end times
+// are represented as seconds since the Unix epoch, and the duration in
+// seconds.
+func (r BgJob) Map() vals.Map {
+	m := vals.MakeMap("id", r.ID, "src", r.Source, "start", vals.UnixSeconds(r.Start))
+	if r.End.IsZero() {
+		return m
+	}
+	var exc, exit any
+	if r.Err != nil {
+		exc = r.Err
+		if reason, ok := Reason(r.Err).(ExternalCmdExit); ok {
+			exit = reason
+		}
+	}
+	return m.
+		Assoc("end", vals.UnixSeconds(r.End)).
+		Assoc("duration", r.End.Sub(r.Start).Seconds()).
+		Assoc("status", ExitStatus(r.Err)).
+		Assoc("exception", exc).
+		Assoc("exit", exit)
+}
+
+func runBgJobHooks(hooks []func(BgJob), r BgJob) {
+	for _, hook := range hooks {
+		hook(r)
+	}
+}
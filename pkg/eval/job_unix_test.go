@@ -0,0 +1,163 @@
+//go:build unix
+
+package eval_test
+
+import (
+	"context"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	. "src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/testutil"
+)
+
+// A command that stops itself, and exits with 3 after being resumed.
+const stoppingCmd = "sh -c 'kill -STOP $$; exit 3'"
+
+func TestJobControl_StopAndFg(t *testing.T) {
+	ev, notes := setupJobControl(t)
+
+	err := evalWithJobControl(ev, stoppingCmd)
+	if err != nil {
+		t.Fatalf("got error %v when the job is stopped, want nil", err)
+	}
+	wantNote(t, notes, "job "+stoppingCmd+" stopped, id = 1")
+	jobs := getJobs(t, ev)
+	if len(jobs) != 1 || jobs[0]["id"] != 1 || jobs[0]["state"] != "stopped" ||
+		jobs[0]["source"] != stoppingCmd {
+		t.Fatalf("got jobs %v, want one stopped job", jobs)
+	}
+
+	err = evalWithJobControl(ev, "fg %1")
+	if err == nil || !strings.Contains(err.Error(), "exited with 3") {
+		t.Errorf("got error %v from fg, want exit status 3", err)
+	}
+	if jobs := getJobs(t, ev); len(jobs) != 0 {
+		t.Errorf("got jobs %v after fg, want none", jobs)
+	}
+}
+
+func TestJobControl_StopAndBg(t *testing.T) {
+	ev, notes := setupJobControl(t)
+
+	evalWithJobControl(ev, stoppingCmd)
+	wantNote(t, notes, "job "+stoppingCmd+" stopped, id = 1")
+
+	err := evalWithJobControl(ev, "bg")
+	if err != nil {
+		t.Errorf("got error %v from bg, want nil", err)
+	}
+	wantNote(t, notes, "job "+stoppingCmd+" finished, errors = sh exited with 3")
+	if jobs := getJobs(t, ev); len(jobs) != 0 {
+		t.Errorf("got jobs %v after the job finished, want none", jobs)
+	}
+}
+
+func TestJobControl_CodeAfterStoppedCmd(t *testing.T) {
+	ev, notes := setupJobControl(t)
+
+	code := "fn f { try { sh -c 'kill -STOP $$' } catch { }; fail after }; f"
+	// Like in the REPL, the interrupt context of the evaluation is canceled
+	// when it finishes, which doesn't affect the job.
+	ctx, cancel := context.WithCancel(context.Background())
+	ev.Eval(parse.Source{Name: "[test]", Code: code},
+		EvalCfg{JobControl: true, Interrupts: ctx})
+	cancel()
+	wantNote(t, notes, "job f stopped, id = 1")
+	jobs := getJobs(t, ev)
+	if len(jobs) != 1 {
+		t.Fatalf("got jobs %v, want one job", jobs)
+	}
+	pid, _ := jobs[0]["pids"].(vals.List).Index(0)
+
+	// The code after the command doesn't run when the command exits while the
+	// job is stopped...
+	syscall.Kill(pid.(int), syscall.SIGKILL)
+	select {
+	case note := <-notes:
+		t.Errorf("got notification %q before the job is resumed", note)
+	case <-time.After(testutil.Scaled(10 * time.Millisecond)):
+	}
+
+	// ...but only after the job is resumed.
+	err := evalWithJobControl(ev, "bg")
+	if err != nil {
+		t.Errorf("got error %v from bg, want nil", err)
+	}
+	wantNote(t, notes, "job f finished, errors = after")
+}
+
+func TestJobControl_Disown(t *testing.T) {
+	ev, notes := setupJobControl(t)
+
+	evalWithJobControl(ev, stoppingCmd)
+	wantNote(t, notes, "job "+stoppingCmd+" stopped, id = 1")
+	jobs := getJobs(t, ev)
+	if len(jobs) != 1 {
+		t.Fatalf("got jobs %v, want one job", jobs)
+	}
+	pid, _ := jobs[0]["pids"].(vals.List).Index(0)
+
+	err := evalWithJobControl(ev, "disown %1")
+	if err != nil {
+		t.Errorf("got error %v from disown, want nil", err)
+	}
+	if jobs := getJobs(t, ev); len(jobs) != 0 {
+		t.Errorf("got jobs %v after disown, want none", jobs)
+	}
+	syscall.Kill(pid.(int), syscall.SIGKILL)
+	select {
+	case note := <-notes:
+		t.Errorf("got notification %q for disowned job", note)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func setupJobControl(t *testing.T) (*Evaler, chan string) {
+	ev := NewEvaler()
+	notes := make(chan string, 10)
+	ev.BgJobNotify = func(s string) { notes <- s }
+	return ev, notes
+}
+
+func evalWithJobControl(ev *Evaler, code string) error {
+	return ev.Eval(parse.Source{Name: "[test]", Code: code}, EvalCfg{JobControl: true})
+}
+
+func getJobs(t *testing.T, ev *Evaler) []map[string]any {
+	t.Helper()
+	port, collect, err := ValueCapturePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ev.Eval(parse.Source{Name: "[test]", Code: "jobs"}, EvalCfg{Ports: []*Port{nil, port}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jobs []map[string]any
+	for _, v := range collect() {
+		job := make(map[string]any)
+		for it := v.(vals.Map).Iterator(); it.HasElem(); it.Next() {
+			k, v := it.Elem()
+			job[k.(string)] = v
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func wantNote(t *testing.T, notes <-chan string, want string) {
+	t.Helper()
+	select {
+	case note := <-notes:
+		if note != want {
+			t.Errorf("got notification %q, want %q", note, want)
+		}
+	case <-time.After(testutil.Scaled(5 * time.Second)):
+		t.Errorf("timed out waiting for notification %q", want)
+	}
+}
@@ -36,6 +36,10 @@ type JobController interface {
 	// TerminateJob forcefully terminates all processes in a job.
 	TerminateJob(jobID JobID) error
 
+	// ReleaseJob stops tracking a job whose processes have all exited, and
+	// releases resources associated with it.
+	ReleaseJob(jobID JobID) error
+
 	// Close cleans up resources associated with the job controller.
 	Close() error
 }
@@ -66,7 +66,7 @@ func TestJobControllerWithCurrentProcess(t *testing.T) {
 
 func TestFgCommandBasic(t *testing.T) {
 	// Test that fg function exists and handles empty arguments correctly
-	err := fg()
+	err := fgPIDs()
 	if err == nil {
 		t.Error("fg() should return an error when called with no arguments")
 	}
@@ -96,7 +96,7 @@ func TestFgCommandIntegration(t *testing.T) {
 
 	// For now, just verify that fg handles invalid PIDs gracefully
 	invalidPid := 999999 // Very unlikely to be a real PID
-	err := fg(invalidPid)
+	err := fgPIDs(invalidPid)
 	if err == nil {
 		t.Error("fg should return an error for invalid PID")
 	}
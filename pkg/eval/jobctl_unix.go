@@ -7,8 +7,6 @@ import (
 	"strconv"
 	"sync"
 	"syscall"
-
-	"src.elv.sh/pkg/sys/eunix"
 )
 
 // unixJobController implements JobController for Unix systems using process groups.
@@ -100,7 +98,7 @@ func (c *unixJobController) BringToForeground(jobID JobID) error {
 	pgid := unixID.pgid
 
 	// Set the process group as the foreground process group
-	if err := eunix.Tcsetpgrp(0, pgid); err != nil {
+	if err := tcsetpgrp(pgid); err != nil {
 		return err
 	}
 
@@ -121,7 +119,7 @@ func (c *unixJobController) SendToBackground(jobID JobID) error {
 	// On Unix, sending to background means giving terminal control back to shell
 	// and ensuring the process group continues running
 	shellPgid := syscall.Getpgrp()
-	if err := eunix.Tcsetpgrp(0, shellPgid); err != nil {
+	if err := tcsetpgrp(shellPgid); err != nil {
 		return err
 	}
 
@@ -236,6 +234,19 @@ func (c *unixJobController) TerminateJob(jobID JobID) error {
 	return nil
 }
 
+func (c *unixJobController) ReleaseJob(jobID JobID) error {
+	unixID, ok := jobID.(*unixJobID)
+	if !ok {
+		return ErrInvalidJobID
+	}
+
+	c.mu.Lock()
+	delete(c.jobs, unixID.pgid)
+	c.mu.Unlock()
+
+	return nil
+}
+
 func (c *unixJobController) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
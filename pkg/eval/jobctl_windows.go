@@ -342,6 +342,19 @@ func (c *windowsJobController) TerminateJob(jobID JobID) error {
 	return windows.CloseHandle(winID.handle)
 }
 
+func (c *windowsJobController) ReleaseJob(jobID JobID) error {
+	winID, ok := jobID.(*windowsJobID)
+	if !ok || !winID.IsValid() {
+		return ErrInvalidJobID
+	}
+
+	c.mu.Lock()
+	delete(c.jobs, winID.handle)
+	c.mu.Unlock()
+
+	return windows.CloseHandle(winID.handle)
+}
+
 func (c *windowsJobController) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
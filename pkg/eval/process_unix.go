@@ -5,6 +5,7 @@ package eval
 import (
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	"src.elv.sh/pkg/sys"
@@ -13,18 +14,81 @@ import (
 
 // Process control functions in Unix.
 
+const jobControlSupported = true
+
 func putSelfInFg() error {
+	return tcsetpgrp(syscall.Getpgrp())
+}
+
+var tcsetpgrpMutex sync.Mutex
+
+// Sets the foreground process group of the terminal on stdin. Does nothing if
+// stdin is not a terminal.
+func tcsetpgrp(pgid int) error {
 	if !sys.IsATTY(os.Stdin.Fd()) {
 		return nil
 	}
 	// If Elvish is in the background, the tcsetpgrp call below will either fail
 	// (if the process is in an orphaned process group) or stop the process.
 	// Ignoring TTOU fixes that.
-	signal.Ignore(syscall.SIGTTOU)
-	defer signal.Reset(syscall.SIGTTOU)
-	return eunix.Tcsetpgrp(0, syscall.Getpgrp())
+	tcsetpgrpMutex.Lock()
+	defer tcsetpgrpMutex.Unlock()
+	if !signal.Ignored(syscall.SIGTTOU) {
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+	}
+	return eunix.Tcsetpgrp(0, pgid)
 }
 
 func makeSysProcAttr(bg bool) *syscall.SysProcAttr {
 	return &syscall.SysProcAttr{Setpgid: bg}
 }
+
+// Returns the SysProcAttr for starting a process in the process group of a
+// job, or a new process group if the job has none.
+func jobSysProcAttr(id JobID) *syscall.SysProcAttr {
+	if id, ok := id.(*unixJobID); ok && id.IsValid() {
+		return &syscall.SysProcAttr{Setpgid: true, Pgid: id.pgid}
+	}
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// Gives the terminal to the process group of a job.
+func giveTerminal(id JobID) error {
+	if id, ok := id.(*unixJobID); ok && id.IsValid() {
+		return tcsetpgrp(id.pgid)
+	}
+	return nil
+}
+
+// Waits for a process to exit. If the process is part of a job with job
+// control, stopping of the process is also reported to the job.
+func waitProcess(proc *os.Process, j *job) (syscall.WaitStatus, error) {
+	if j == nil || !j.control {
+		state, err := proc.Wait()
+		if err != nil {
+			return 0, err
+		}
+		return state.Sys().(syscall.WaitStatus), nil
+	}
+	for {
+		var ws syscall.WaitStatus
+		_, err := syscall.Wait4(proc.Pid, &ws, syscall.WUNTRACED, nil)
+		if err == syscall.EINTR {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		if !ws.Stopped() {
+			proc.Release()
+			return ws, nil
+		}
+		if sig := ws.StopSignal(); (sig == syscall.SIGTTIN || sig == syscall.SIGTTOU) && j.inFg() {
+			// The process has accessed the terminal before the job was given
+			// the terminal; it can continue now.
+			syscall.Kill(proc.Pid, syscall.SIGCONT)
+			continue
+		}
+		j.processStopped()
+	}
+}
@@ -1,6 +1,13 @@
 package eval
 
-import "syscall"
+import (
+	"os"
+	"syscall"
+)
+
+// Job control is not supported on Windows; the job table only contains
+// background jobs.
+const jobControlSupported = false
 
 // Nop on Windows.
 func putSelfInFg() error { return nil }
@@ -22,3 +29,17 @@ func makeSysProcAttr(bg bool) *syscall.SysProcAttr {
 
 	return &syscall.SysProcAttr{CreationFlags: flags}
 }
+
+// Never called since job control is not supported on Windows.
+func jobSysProcAttr(JobID) *syscall.SysProcAttr { return makeSysProcAttr(false) }
+
+// Nop on Windows.
+func giveTerminal(JobID) error { return nil }
+
+func waitProcess(proc *os.Process, _ *job) (syscall.WaitStatus, error) {
+	state, err := proc.Wait()
+	if err != nil {
+		return syscall.WaitStatus{}, err
+	}
+	return state.Sys().(syscall.WaitStatus), nil
+}
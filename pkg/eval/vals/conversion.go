@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"src.elv.sh/pkg/eval/errs"
@@ -329,3 +330,9 @@ func FromGo(a any) any {
 		return a
 	}
 }
+
+// UnixSeconds converts a time to the number of seconds since the Unix epoch,
+// which is how times are represented in Elvish values.
+func UnixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
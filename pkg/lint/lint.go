@@ -0,0 +1,443 @@
+// Package lint implements a static checker for Elvish code.
+//
+// The checker finds code that compiles but is likely to be a mistake, like
+// unused variables and unreachable code. It models scoping rules of the
+// compiler on the parse tree; code that introduces variables dynamically (like
+// eval) is not taken into account.
+package lint
+
+import (
+	"slices"
+	"strings"
+
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/parse/cmpd"
+	"src.elv.sh/pkg/prog"
+)
+
+// Warning is a problem found by the linter.
+type Warning = diag.Error[WarningTag]
+
+// WarningTag parameterizes [diag.Error] to define [Warning].
+type WarningTag struct{}
+
+func (WarningTag) ErrorTag() string { return "warning" }
+
+// Lint checks a parse tree, using builtin to resolve names that are not
+// defined in the code. The warnings are sorted by their positions.
+func Lint(builtin *eval.Ns, tree parse.Tree) []*Warning {
+	l := &linter{src: tree.Source, builtin: builtin}
+	// Variables and functions defined at the top level are exported when the
+	// code is used as a module, so they are not reported as unused.
+	l.pushScope(false)
+	l.chunk(tree.Root)
+	l.popScope()
+	slices.SortStableFunc(l.warnings, func(a, b *Warning) int {
+		return a.Context.From - b.Context.From
+	})
+	return l.warnings
+}
+
+type linter struct {
+	src      parse.Source
+	builtin  *eval.Ns
+	scopes   []*scope
+	warnings []*Warning
+}
+
+type scope struct {
+	vars map[string]*variable
+	// Whether unused variables and functions are reported.
+	checkUnused bool
+}
+
+type variable struct {
+	// Qualified name, with eval.FnSuffix for functions and eval.NsSuffix for
+	// namespaces imported with use.
+	name string
+	node diag.Ranger
+	// Whether the variable should be reported if it's never read.
+	checkUnused bool
+	read, set   bool
+}
+
+func (l *linter) warn(r diag.Ranger, msg string) {
+	l.warnings = append(l.warnings, &Warning{
+		Message: msg, Context: *diag.NewContext(l.src.Name, l.src.Code, r)})
+}
+
+func (l *linter) pushScope(checkUnused bool) {
+	l.scopes = append(l.scopes, &scope{map[string]*variable{}, checkUnused})
+}
+
+func (l *linter) popScope() {
+	sc := l.scopes[len(l.scopes)-1]
+	l.scopes = l.scopes[:len(l.scopes)-1]
+	for _, v := range sc.vars {
+		l.checkUnused(v)
+	}
+}
+
+func (l *linter) checkUnused(v *variable) {
+	if v.read || !v.checkUnused {
+		return
+	}
+	switch {
+	case strings.HasSuffix(v.name, eval.FnSuffix):
+		l.warn(v.node, "function "+strings.TrimSuffix(v.name, eval.FnSuffix)+" is never used")
+	case strings.HasSuffix(v.name, eval.NsSuffix):
+		l.warn(v.node, "module "+strings.TrimSuffix(v.name, eval.NsSuffix)+" is imported but never used")
+	case v.set:
+		l.warn(v.node, "variable $"+v.name+" is assigned but never read")
+	default:
+		l.warn(v.node, "variable $"+v.name+" is never used")
+	}
+}
+
+// Defines a variable in the current scope. Variables defined explicitly with
+// var, fn or use are checked for being unused or shadowing builtins; other
+// variables, like parameters, are required by the syntax and not checked.
+func (l *linter) define(name string, n diag.Ranger, explicit bool) {
+	sc := l.scopes[len(l.scopes)-1]
+	if old, ok := sc.vars[name]; ok {
+		// The old variable can no longer be used.
+		l.checkUnused(old)
+	}
+	// Unused imports are reported even at the top level.
+	isNs := strings.HasSuffix(name, eval.NsSuffix)
+	sc.vars[name] = &variable{name: name, node: n, checkUnused: explicit && (sc.checkUnused || isNs)}
+	if !explicit {
+		return
+	}
+	switch {
+	case strings.HasSuffix(name, eval.FnSuffix):
+		fnName := strings.TrimSuffix(name, eval.FnSuffix)
+		if eval.IsBuiltinSpecial[fnName] {
+			l.warn(n, "function "+fnName+" shadows a builtin special command")
+		} else if l.builtin.HasKeyString(name) {
+			l.warn(n, "function "+fnName+" shadows a builtin function")
+		}
+	case isNs:
+		// Namespaces imported with use are never looked up in the builtin
+		// namespace.
+	case name == "_":
+		// Conventionally used for discarding values.
+	default:
+		if l.builtin.HasKeyString(name) {
+			l.warn(n, "variable $"+name+" shadows a builtin variable")
+		}
+	}
+}
+
+// Resolves a qualified name, following the same rules as the compiler. It
+// returns the variable if the name resolves to one defined in the code;
+// otherwise it returns the name that should be looked up in the builtin
+// namespace, or "" if the name can't be builtin.
+func (l *linter) resolve(qname string) (*variable, string) {
+	scopes := l.scopes
+	switch first, rest := eval.SplitQName(qname); first {
+	case "local:":
+		scopes, qname = scopes[len(scopes)-1:], rest
+	case "up:":
+		scopes, qname = scopes[:len(scopes)-1], rest
+	case "builtin:":
+		return nil, rest
+	}
+	name := qname
+	if first, rest := eval.SplitQName(qname); rest != "" {
+		// Only the namespace can be defined in the code.
+		name = first
+	}
+	for i := len(scopes) - 1; i >= 0; i-- {
+		if v, ok := scopes[i].vars[name]; ok {
+			return v, ""
+		}
+	}
+	return nil, qname
+}
+
+// Records a read of a variable.
+func (l *linter) read(qname string, n diag.Ranger) {
+	v, builtinName := l.resolve(qname)
+	if v != nil {
+		v.read = true
+	} else if builtinName != "" && l.builtin.HasKeyString(builtinName) {
+		if msg, minLevel, ok := eval.DeprecatedBuiltin(builtinName); ok && prog.DeprecationLevel >= minLevel {
+			l.warn(n, msg)
+		}
+	}
+}
+
+// Records an assignment to a variable.
+func (l *linter) set(qname string) {
+	if v, _ := l.resolve(qname); v != nil {
+		if strings.HasSuffix(v.name, eval.NsSuffix) {
+			// Setting a variable in a namespace.
+			v.read = true
+		} else {
+			v.set = true
+		}
+	}
+}
+
+func (l *linter) chunk(n *parse.Chunk) {
+	for i, pn := range n.Pipelines {
+		l.pipeline(pn)
+		if i < len(n.Pipelines)-1 && l.terminates(pn) {
+			last := n.Pipelines[len(n.Pipelines)-1]
+			// Pipelines include trailing spaces; exclude them from the range.
+			to := last.From + len(strings.TrimRight(parse.SourceText(last), " \t"))
+			l.warn(diag.Ranging{From: n.Pipelines[i+1].From, To: to}, "unreachable code")
+			for _, pn := range n.Pipelines[i+1:] {
+				l.pipeline(pn)
+			}
+			return
+		}
+	}
+}
+
+// Reports whether the pipeline always stops the execution of the chunk
+// containing it.
+func (l *linter) terminates(n *parse.Pipeline) bool {
+	if len(n.Forms) != 1 || n.Background {
+		return false
+	}
+	switch head, _ := cmpd.StringLiteral(n.Forms[0].Head); head {
+	case "return", "fail", "break", "continue":
+		v, _ := l.resolve(head + eval.FnSuffix)
+		return v == nil
+	}
+	return false
+}
+
+func (l *linter) pipeline(n *parse.Pipeline) {
+	for _, fn := range n.Forms {
+		l.form(fn)
+	}
+}
+
+func (l *linter) form(n *parse.Form) {
+	head, ok := cmpd.StringLiteral(n.Head)
+	if !ok {
+		l.walkChildren(n)
+		return
+	}
+	switch head {
+	case "var":
+		l.assignment(n.Args, varLValue)
+	case "set", "tmp":
+		l.assignment(n.Args, setLValue)
+	case "with":
+		if len(n.Args) < 2 {
+			l.walkChildren(n)
+			return
+		}
+		assigns := n.Args[:len(n.Args)-1]
+		if p, ok := cmpd.Primary(assigns[0]); ok && p.Type == parse.List {
+			for _, assign := range assigns {
+				if p, ok := cmpd.Primary(assign); ok {
+					l.assignment(p.Elements, setLValue)
+				}
+			}
+		} else {
+			l.assignment(assigns, setLValue)
+		}
+		l.walk(n.Args[len(n.Args)-1])
+	case "del":
+		for _, arg := range n.Args {
+			l.lvalue(arg, delLValue)
+		}
+	case "fn":
+		if len(n.Args) > 0 {
+			if name, ok := cmpd.StringLiteral(n.Args[0]); ok {
+				// Like the compiler, define the function before walking the
+				// body, so that it may refer to itself.
+				l.define(name+eval.FnSuffix, n.Args[0], true)
+			}
+			for _, arg := range n.Args[1:] {
+				l.walk(arg)
+			}
+		}
+	case "use":
+		var name string
+		var node diag.Ranger
+		switch len(n.Args) {
+		case 1:
+			if spec, ok := cmpd.StringLiteral(n.Args[0]); ok {
+				name, node = spec[strings.LastIndexByte(spec, '/')+1:], n.Args[0]
+			}
+		case 2:
+			if s, ok := cmpd.StringLiteral(n.Args[1]); ok {
+				name, node = s, n.Args[1]
+			}
+		}
+		if name != "" {
+			l.define(name+eval.NsSuffix, node, true)
+		}
+	case "for":
+		if len(n.Args) > 0 {
+			l.lvalue(n.Args[0], newOrSetLValue)
+			for _, arg := range n.Args[1:] {
+				l.walk(arg)
+			}
+		}
+	case "try":
+		for i, arg := range n.Args {
+			prev := ""
+			if i > 0 {
+				prev, _ = cmpd.StringLiteral(n.Args[i-1])
+			}
+			if _, isLiteral := cmpd.StringLiteral(arg); prev == "catch" && isLiteral {
+				l.lvalue(arg, newOrSetLValue)
+			} else {
+				l.walk(arg)
+			}
+		}
+	case "pragma":
+		// Arguments are not evaluated.
+	default:
+		l.read(head+eval.FnSuffix, n.Head)
+		if head == "each" || head == "peach" {
+			l.checkFnArg(head, n)
+		}
+		l.walkChildren(n)
+	}
+}
+
+// Checks that the first argument of each or peach is a function.
+func (l *linter) checkFnArg(head string, n *parse.Form) {
+	if v, _ := l.resolve(head + eval.FnSuffix); v != nil || len(n.Args) == 0 {
+		return
+	}
+	p, ok := cmpd.Primary(n.Args[0])
+	if !ok {
+		return
+	}
+	var what string
+	switch p.Type {
+	case parse.Bareword, parse.SingleQuoted, parse.DoubleQuoted:
+		what = "a string"
+	case parse.List:
+		what = "a list"
+	case parse.Map:
+		what = "a map"
+	default:
+		return
+	}
+	l.warn(n.Args[0], "argument to "+head+" should be a function, but is "+what+
+		"; use a lambda or a function variable like $echo~")
+}
+
+type lvalueKind int
+
+const (
+	// Defines a new variable, as in var.
+	varLValue lvalueKind = iota
+	// Sets an existing variable or defines a new one, as in for and catch.
+	newOrSetLValue
+	// Sets an existing variable, as in set, tmp and with.
+	setLValue
+	// Deletes a variable, as in del.
+	delLValue
+)
+
+// Walks an assignment in var, set, tmp or with: LHS and optional RHS separated
+// by "=". The RHS is evaluated before the LHS.
+func (l *linter) assignment(args []*parse.Compound, kind lvalueKind) {
+	lhs := args
+	for i, arg := range args {
+		if parse.SourceText(arg) == "=" {
+			lhs = args[:i]
+			for _, rhs := range args[i+1:] {
+				l.walk(rhs)
+			}
+			break
+		}
+	}
+	for _, arg := range lhs {
+		l.lvalue(arg, kind)
+	}
+}
+
+func (l *linter) lvalue(n *parse.Compound, kind lvalueKind) {
+	if len(n.Indexings) != 1 || n.Indexings[0].Head.Type != parse.Bareword {
+		l.walk(n)
+		return
+	}
+	in := n.Indexings[0]
+	_, name := eval.SplitSigil(in.Head.Value)
+	for _, index := range in.Indices {
+		l.walk(index)
+	}
+	if len(in.Indices) > 0 || kind == delLValue {
+		// Setting or deleting an element reads the container.
+		l.read(name, in.Head)
+		return
+	}
+	switch kind {
+	case varLValue:
+		l.define(name, in.Head, true)
+	case newOrSetLValue:
+		if v, _ := l.resolve(name); v != nil {
+			l.set(name)
+		} else {
+			l.define(name, in.Head, false)
+		}
+	case setLValue:
+		l.set(name)
+	}
+}
+
+// Walks an arbitrary node.
+func (l *linter) walk(n parse.Node) {
+	switch n := n.(type) {
+	case *parse.Form:
+		l.form(n)
+		return
+	case *parse.Chunk:
+		l.chunk(n)
+		return
+	case *parse.Primary:
+		switch n.Type {
+		case parse.Variable:
+			_, qname := eval.SplitSigil(n.Value)
+			l.read(qname, n)
+			return
+		case parse.Lambda:
+			l.lambda(n)
+			return
+		}
+	}
+	l.walkChildren(n)
+}
+
+func (l *linter) walkChildren(n parse.Node) {
+	for _, ch := range parse.Children(n) {
+		l.walk(ch)
+	}
+}
+
+func (l *linter) lambda(n *parse.Primary) {
+	// Default values of options are evaluated in the enclosing scope.
+	for _, opt := range n.MapPairs {
+		if opt.Value != nil {
+			l.walk(opt.Value)
+		}
+	}
+	l.pushScope(true)
+	for _, param := range n.Elements {
+		if s, ok := cmpd.StringLiteral(param); ok {
+			_, name := eval.SplitSigil(s)
+			l.define(name, param, false)
+		}
+	}
+	for _, opt := range n.MapPairs {
+		if name, ok := cmpd.StringLiteral(opt.Key); ok {
+			l.define(name, opt.Key, false)
+		}
+	}
+	l.chunk(n.Chunk)
+	l.popScope()
+}
@@ -0,0 +1,149 @@
+package lint_test
+
+import (
+	"slices"
+	"testing"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/lint"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/prog"
+	"src.elv.sh/pkg/testutil"
+)
+
+// A warning, identified by its message and the source text it applies to.
+type warning struct {
+	message string
+	text    string
+}
+
+var lintTests = []struct {
+	name string
+	code string
+	want []warning
+}{
+	{name: "no problems", code: "var x = foo; echo $x"},
+
+	{name: "unused variable",
+		code: "fn f { var x = foo; var y; echo $y }",
+		want: []warning{{"variable $x is never used", "x"}}},
+	{name: "unused variable in nested lambda",
+		code: "fn f { if $true { var x } }",
+		want: []warning{{"variable $x is never used", "x"}}},
+	{name: "variable used in closure",
+		code: "fn f { var x; put { echo $x } }"},
+	{name: "variable used with local: or up:",
+		code: "fn f { var x; var y; echo $local:x; put { echo $up:y } }"},
+	{name: "variable set but not read",
+		code: "fn f { var x = foo; set x = bar }",
+		want: []warning{{"variable $x is assigned but never read", "x"}}},
+	{name: "variable set in closure but not read",
+		code: "fn f { var x; put { set x = bar } }",
+		want: []warning{{"variable $x is assigned but never read", "x"}}},
+	{name: "variable read in its own assignment",
+		code: "fn f { var x = 0; set x = (+ $x 1) }"},
+	{name: "setting an element reads the variable",
+		code: "fn f { var m = [&]; set m[k] = v }"},
+	{name: "del reads the variable",
+		code: "fn f { var x; del x }"},
+	{name: "redefined variable",
+		code: "fn f { var x = foo; var x = bar; echo $x }",
+		want: []warning{{"variable $x is never used", "x"}}},
+	{name: "unused function",
+		code: "fn f { fn g { } }",
+		want: []warning{{"function g is never used", "g"}}},
+	{name: "function used",
+		code: "fn f { fn g { }; g }"},
+	{name: "unused import",
+		code: "use str; use a/b; use c d; echo $b:x",
+		want: []warning{
+			{"module str is imported but never used", "str"},
+			{"module d is imported but never used", "d"},
+		}},
+	{name: "import used in command",
+		code: "use str; str:join , [a b]"},
+	{name: "top-level variables and functions are not reported",
+		code: "var x; fn f { }"},
+	{name: "parameters and for and catch variables are not reported",
+		code: "fn f {|a &b=c| for x [] { }; try { } catch e { } }"},
+	{name: "option default values are evaluated in the outer scope",
+		code: "fn f { var x; put {|&a=$x| } }"},
+
+	{name: "unreachable code after return",
+		code: "fn f { return\necho a\necho b }",
+		want: []warning{{"unreachable code", "echo a\necho b"}}},
+	{name: "unreachable code after fail",
+		code: "fail x; echo a",
+		want: []warning{{"unreachable code", "echo a"}}},
+	{name: "unreachable code after break and continue",
+		code: "while $true { break; echo a }; while $true { continue; echo b }",
+		want: []warning{{"unreachable code", "echo a"}, {"unreachable code", "echo b"}}},
+	{name: "return in a pipeline is not terminating",
+		code: "fn f { return | nop; echo a }"},
+	{name: "return in background is not terminating",
+		code: "fn f { return &\necho a }"},
+	{name: "shadowed return is not terminating",
+		code: "fn return { }; return; echo a",
+		want: []warning{{"function return shadows a builtin function", "return"}}},
+	{name: "unreachable code is still checked",
+		code: "fn f { return; var x }",
+		want: []warning{{"unreachable code", "var x"}, {"variable $x is never used", "x"}}},
+
+	{name: "shadowed builtin function",
+		code: "fn put { }",
+		want: []warning{{"function put shadows a builtin function", "put"}}},
+	{name: "shadowed builtin special command",
+		code: "fn if { }",
+		want: []warning{{"function if shadows a builtin special command", "if"}}},
+	{name: "shadowed builtin variable",
+		code: "var paths",
+		want: []warning{{"variable $paths shadows a builtin variable", "paths"}}},
+	{name: "parameters and _ are not checked for shadowing",
+		code: "var _; fn f {|paths| }"},
+
+	{name: "each with string argument",
+		code: "each echo",
+		want: []warning{{"argument to each should be a function, but is a string; use a lambda or a function variable like $echo~", "echo"}}},
+	{name: "peach with list argument",
+		code: "peach [a b]",
+		want: []warning{{"argument to peach should be a function, but is a list; use a lambda or a function variable like $echo~", "[a b]"}}},
+	{name: "each with function arguments",
+		code: "each $echo~; each {|x| }; var f = $put~; each $f"},
+	{name: "shadowed each is not checked",
+		code: "fn each {|f| }; each echo",
+		want: []warning{{"function each shadows a builtin function", "each"}}},
+}
+
+func TestLint(t *testing.T) {
+	builtin := eval.NewEvaler().Builtin()
+	for _, tc := range lintTests {
+		t.Run(tc.name, func(t *testing.T) {
+			tree, err := parse.Parse(parse.Source{Name: "[test]", Code: tc.code}, parse.Config{})
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			var got []warning
+			for _, w := range lint.Lint(builtin, tree) {
+				got = append(got, warning{w.Message, tc.code[w.Context.From:w.Context.To]})
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLint_DeprecatedBuiltin(t *testing.T) {
+	testutil.Set(t, &prog.DeprecationLevel, 22)
+	ev := eval.NewEvaler()
+	// The deprecation data contains a fake entry "foo", which is only checked
+	// when it is actually a builtin.
+	ev.ExtendBuiltin(eval.BuildNs().AddGoFn("foo", func() {}))
+	tree, _ := parse.Parse(parse.Source{Name: "[test]", Code: "foo; fn f { foo }"}, parse.Config{})
+
+	warnings := lint.Lint(ev.Builtin(), tree)
+
+	if len(warnings) != 2 || warnings[0].Message != `the "foo" command is deprecated; use "bar" instead` {
+		t.Errorf("got %v, want 2 deprecation warnings", warnings)
+	}
+}
@@ -0,0 +1,108 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/prog"
+)
+
+// Program is the linter subprogram.
+type Program struct {
+	run  bool
+	json *bool
+}
+
+func (p *Program) RegisterFlags(fs *prog.FlagSet) {
+	fs.BoolVar(&p.run, "lint", false,
+		"Check Elvish source files for errors and likely mistakes")
+	p.json = fs.JSON()
+}
+
+func (p *Program) Run(fds [3]*os.File, files []string) error {
+	if !p.run {
+		return prog.NextProgram()
+	}
+	if len(files) == 0 {
+		return prog.BadUsage("-lint requires at least one file")
+	}
+	ev := eval.NewEvaler()
+	var errs []error
+	for _, file := range files {
+		code, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, check(ev, parse.Source{Name: file, Code: string(code), IsFile: true})...)
+	}
+	if *p.json {
+		// Always output an array, even if there are no problems.
+		problems := make([]problemInJSON, len(errs))
+		for i, err := range errs {
+			problems[i] = toJSON(err)
+		}
+		fmt.Fprintf(fds[1], "%s\n", must.OK1(json.Marshal(problems)))
+	} else {
+		for _, err := range errs {
+			diag.ShowError(fds[2], err)
+		}
+	}
+	if len(errs) > 0 {
+		return prog.Exit(2)
+	}
+	return nil
+}
+
+// Returns all the parse errors, compilation errors and warnings in src.
+func check(ev *eval.Evaler, src parse.Source) []error {
+	tree, parseErr := parse.Parse(src, parse.Config{})
+	if parseErr != nil {
+		return convertErrors(parse.UnpackErrors(parseErr))
+	}
+	var errs []error
+	_, compileErr := ev.CheckTree(tree, nil)
+	errs = append(errs, convertErrors(eval.UnpackCompilationErrors(compileErr))...)
+	errs = append(errs, convertErrors(Lint(ev.Builtin(), tree))...)
+	return errs
+}
+
+func convertErrors[T diag.ErrorTag](errs []*diag.Error[T]) []error {
+	converted := make([]error, len(errs))
+	for i, err := range errs {
+		converted[i] = err
+	}
+	return converted
+}
+
+// An auxiliary struct for converting problems to JSON, using the same format
+// as -compileonly.
+type problemInJSON struct {
+	FileName string `json:"fileName"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Message  string `json:"message"`
+	// One of "parse error", "compilation error" and "warning".
+	Type string `json:"type"`
+}
+
+func toJSON(err error) problemInJSON {
+	switch err := err.(type) {
+	case *parse.Error:
+		return problemToJSON(err)
+	case *eval.CompilationError:
+		return problemToJSON(err)
+	case *Warning:
+		return problemToJSON(err)
+	}
+	panic("unreachable")
+}
+
+func problemToJSON[T diag.ErrorTag](err *diag.Error[T]) problemInJSON {
+	var tag T
+	return problemInJSON{err.Context.Name, err.Context.From, err.Context.To, err.Message, tag.ErrorTag()}
+}
@@ -0,0 +1,278 @@
+package lsp
+
+import (
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	lsp "pkg.nimblebun.works/go-lsp"
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/parse/cmpd"
+)
+
+// reference is an occurrence of a variable name in a parse tree, either as a
+// use (like $x or the command head f) or at its definition site (like the x in
+// var x).
+type reference struct {
+	// Qualified variable name, with eval.FnSuffix for functions and
+	// eval.NsSuffix for namespaces.
+	qname string
+	// The node carrying the name.
+	node parse.Node
+	// Range of the last segment of the name; for $str:x this is the range of x.
+	nameRange diag.Ranging
+	// The definition site, if this reference is one.
+	def *parse.Compound
+}
+
+// definition identifies where a variable is defined.
+type definition struct {
+	uri  lsp.DocumentURI
+	node *parse.Compound
+	// Unqualified name of the variable.
+	name string
+}
+
+func (d *definition) sameAs(d2 *definition) bool {
+	return d != nil && d2 != nil && d.uri == d2.uri && d.node.Range() == d2.node.Range()
+}
+
+func newReference(qname string, n parse.Node) reference {
+	name := strings.TrimSuffix(strings.TrimSuffix(qname, eval.FnSuffix), eval.NsSuffix)
+	ns, name := eval.SplitIncompleteQNameNs(name)
+	// Find the name after the "$" and sigil of variables and the namespace
+	// prefix, and before the suffix of functions and namespaces. The last
+	// occurrence is used, so that the name in "use a/b" is the b.
+	from := n.Range().From
+	if i := strings.LastIndex(parse.SourceText(n), ns+name); i != -1 {
+		from += i + len(ns)
+	}
+	return reference{qname: qname, node: n, nameRange: diag.Ranging{From: from, To: from + len(name)}}
+}
+
+// eachReference calls f for each reference in the tree rooted at n, in
+// the order they appear in the source.
+func eachReference(n parse.Node, f func(reference)) {
+	switch n := n.(type) {
+	case *parse.Primary:
+		switch n.Type {
+		case parse.Variable:
+			_, qname := eval.SplitSigil(n.Value)
+			f(newReference(qname, n))
+		case parse.Lambda:
+			for _, param := range n.Elements {
+				if varRef, ok := cmpd.StringLiteral(param); ok {
+					_, name := eval.SplitSigil(varRef)
+					ref := newReference(name, param)
+					ref.def = param
+					f(ref)
+				}
+			}
+		}
+	case *parse.Form:
+		if n.Head != nil {
+			if head, ok := cmpd.StringLiteral(n.Head); ok {
+				f(newReference(head+eval.FnSuffix, n.Head))
+			}
+		}
+		eachDefinedVariableInForm(n, func(name string, def *parse.Compound) {
+			ref := newReference(name, def)
+			ref.def = def
+			f(ref)
+		})
+	}
+	for _, ch := range parse.Children(n) {
+		eachReference(ch, f)
+	}
+}
+
+// referenceAt finds the innermost reference whose node covers pos.
+func referenceAt(root parse.Node, pos int) (reference, bool) {
+	var found reference
+	ok := false
+	eachReference(root, func(ref reference) {
+		if r := ref.node.Range(); r.From <= pos && pos <= r.To {
+			found, ok = ref, true
+		}
+	})
+	return found, ok
+}
+
+// findDefinition finds the innermost definition of the unqualified name qname
+// visible at pos.
+func findDefinition(root parse.Node, pos int, qname string) *parse.Compound {
+	var found *parse.Compound
+	eachDefinedVariableAtPos(root, pos, func(name string, def *parse.Compound) {
+		if name == qname {
+			found = def
+		}
+	})
+	return found
+}
+
+// resolve finds the definition of ref, which appears in the document at uri.
+// It returns nil if the definition can't be found, for example when ref refers
+// to a builtin or an environment variable.
+func (s *server) resolve(uri lsp.DocumentURI, doc document, ref reference) *definition {
+	if ref.def != nil {
+		return &definition{uri, ref.def, ref.qname}
+	}
+	return s.resolveQName(uri, doc, ref.node.Range().From, ref.qname)
+}
+
+func (s *server) resolveQName(uri lsp.DocumentURI, doc document, pos int, qname string) *definition {
+	first, rest := eval.SplitQName(qname)
+	if rest == "" {
+		if def := findDefinition(doc.parseTree.Root, pos, first); def != nil {
+			return &definition{uri, def, first}
+		}
+		return nil
+	}
+	// A qualified name. The only namespaces whose source we can find are
+	// modules imported with use.
+	useNode := findDefinition(doc.parseTree.Root, pos, first)
+	if useNode == nil {
+		return nil
+	}
+	useForm, ok := parse.Parent(useNode).(*parse.Form)
+	if !ok || len(useForm.Args) == 0 {
+		return nil
+	}
+	spec, ok := cmpd.StringLiteral(useForm.Args[0])
+	if !ok {
+		return nil
+	}
+	modURI, ok := s.resolveModule(uri, spec)
+	if !ok {
+		return nil
+	}
+	modDoc, ok := s.loadDocument(modURI)
+	if !ok {
+		return nil
+	}
+	// Only variables defined at the top level of a module are exported.
+	return s.resolveQName(modURI, modDoc, len(modDoc.code), rest)
+}
+
+// resolveModule finds the source file of the module that `use spec` imports
+// from the document at uri, following the same rules as the use special
+// command. Modules that are not backed by .elv files are not found.
+func (s *server) resolveModule(uri lsp.DocumentURI, spec string) (lsp.DocumentURI, bool) {
+	var candidates []string
+	if strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../") {
+		path, ok := uriToPath(uri)
+		if !ok {
+			return "", false
+		}
+		candidates = []string{filepath.Join(filepath.Dir(path), spec)}
+	} else {
+		for _, dir := range s.evaler.LibDirs {
+			candidates = append(candidates, filepath.Join(dir, spec))
+		}
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate + ".elv"); err == nil {
+			return pathToURI(candidate + ".elv"), true
+		}
+	}
+	return "", false
+}
+
+// loadDocument returns the document at uri, reading it from disk if it is not
+// open in the client.
+func (s *server) loadDocument(uri lsp.DocumentURI) (document, bool) {
+	if doc, ok := s.documents[uri]; ok {
+		return doc, true
+	}
+	return s.readFile(uri)
+}
+
+// readFile reads and parses the document at uri from disk. The parsed document
+// is reused until the file is modified. Unlike loadDocument, it can be called
+// from the goroutine indexing the workspace.
+func (s *server) readFile(uri lsp.DocumentURI) (document, bool) {
+	path, ok := uriToPath(uri)
+	if !ok {
+		return document{}, false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return document{}, false
+	}
+	s.mutex.Lock()
+	f, ok := s.files[uri]
+	s.mutex.Unlock()
+	if ok && f.modTime.Equal(info.ModTime()) && f.size == info.Size() {
+		return f.doc, true
+	}
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return document{}, false
+	}
+	tree, err := parse.Parse(parse.Source{Name: path, Code: string(code), IsFile: true}, parse.Config{})
+	doc := document{string(code), tree, err}
+	s.mutex.Lock()
+	s.files[uri] = file{info.ModTime(), info.Size(), doc}
+	s.mutex.Unlock()
+	return doc, true
+}
+
+// findReferences finds all references to def in open documents, indexed
+// workspace files and the document defining it.
+func (s *server) findReferences(def *definition, includeDecl bool) []lsp.Location {
+	uris := []lsp.DocumentURI{def.uri}
+	s.mutex.Lock()
+	others := slices.Concat(slices.Collect(maps.Keys(s.documents)), slices.Collect(maps.Keys(s.symbols)))
+	s.mutex.Unlock()
+	slices.Sort(others)
+	for _, uri := range slices.Compact(others) {
+		if uri != def.uri {
+			uris = append(uris, uri)
+		}
+	}
+	locations := []lsp.Location{}
+	for _, uri := range uris {
+		doc, ok := s.loadDocument(uri)
+		if !ok {
+			continue
+		}
+		eachReference(doc.parseTree.Root, func(ref reference) {
+			if ref.def != nil && !includeDecl {
+				return
+			}
+			if def.sameAs(s.resolve(uri, doc, ref)) {
+				locations = append(locations, lsp.Location{
+					URI: uri, Range: lspRangeFromRange(doc.code, ref.nameRange)})
+			}
+		})
+	}
+	return locations
+}
+
+func uriToPath(uri lsp.DocumentURI) (string, bool) {
+	u, err := url.Parse(string(uri))
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	path := u.Path
+	if runtime.GOOS == "windows" {
+		// file:///C:/foo has the path /C:/foo.
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.FromSlash(path), true
+}
+
+func pathToURI(path string) lsp.DocumentURI {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	u := url.URL{Scheme: "file", Path: path}
+	return lsp.DocumentURI(u.String())
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/sourcegraph/jsonrpc2"
 	"src.elv.sh/pkg/prog"
+	"src.elv.sh/pkg/shell"
 )
 
 // Program is the LSP subprogram.
@@ -25,6 +26,9 @@ func (p *Program) Run(fds [3]*os.File, _ []string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	s := newServer()
+	if libDirs, err := shell.LibPaths(); err == nil {
+		s.evaler.LibDirs = libDirs
+	}
 	conn := jsonrpc2.NewConn(ctx,
 		jsonrpc2.NewBufferedStream(transport{fds[0], fds[1]}, jsonrpc2.VSCodeObjectCodec{}),
 		handler(s))
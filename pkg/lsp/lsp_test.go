@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/sourcegraph/jsonrpc2"
 	lsp "pkg.nimblebun.works/go-lsp"
+	"src.elv.sh/pkg/env"
 	"src.elv.sh/pkg/mods/doc"
 	"src.elv.sh/pkg/must"
 	"src.elv.sh/pkg/prog"
@@ -72,6 +74,47 @@ var diagTests = []struct {
 			Severity: lsp.DSError, Source: "parse", Message: "should be variable name",
 		},
 	}},
+	{"compilation error", "echo $x", []lsp.Diagnostic{
+		{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 0, Character: 5},
+				End:   lsp.Position{Line: 0, Character: 7},
+			},
+			Severity: lsp.DSError, Source: "compile", Message: "variable $x not found",
+		},
+	}},
+	{"multiple compilation errors", "var x\necho $x $y\nfn f { $z }", []lsp.Diagnostic{
+		{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 1, Character: 8},
+				End:   lsp.Position{Line: 1, Character: 10},
+			},
+			Severity: lsp.DSError, Source: "compile", Message: "variable $y not found",
+		},
+		{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 2, Character: 7},
+				End:   lsp.Position{Line: 2, Character: 9},
+			},
+			Severity: lsp.DSError, Source: "compile", Message: "variable $z not found",
+		},
+	}},
+	{"parse and compilation errors", "echo $x\n$!", []lsp.Diagnostic{
+		{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 1, Character: 1},
+				End:   lsp.Position{Line: 1, Character: 2},
+			},
+			Severity: lsp.DSError, Source: "parse", Message: "should be variable name",
+		},
+		{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 0, Character: 5},
+				End:   lsp.Position{Line: 0, Character: 7},
+			},
+			Severity: lsp.DSError, Source: "compile", Message: "variable $x not found",
+		},
+	}},
 }
 
 func TestDidOpenDiagnostics(t *testing.T) {
@@ -97,6 +140,65 @@ func TestDidChangeDiagnostics(t *testing.T) {
 	}
 }
 
+func TestDidChangeDiagnostics_Incremental(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams("echo $x"))
+	checkDiag(t, f, diagParam([]lsp.Diagnostic{
+		{
+			Range:    *lspRange(0, 5, 0, 7),
+			Severity: lsp.DSError, Source: "compile", Message: "variable $x not found",
+		},
+	}))
+
+	f.conn.Notify(bgCtx, "textDocument/didChange", didChangeTextDocumentParams{
+		TextDocument: lsp.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: testURI},
+		},
+		ContentChanges: []contentChangeEvent{
+			// echo $x -> var x\necho $x
+			{Range: lspRange(0, 0, 0, 0), Text: "var x\n"},
+			// var x\necho $x -> var x\necho $x $!
+			{Range: lspRange(1, 7, 1, 7), Text: " $!"},
+			// var x\necho $x $! -> var x\necho $x $y
+			{Range: lspRange(1, 9, 1, 10), Text: "y"},
+		},
+	})
+	checkDiag(t, f, diagParam([]lsp.Diagnostic{
+		{
+			Range:    *lspRange(1, 8, 1, 10),
+			Severity: lsp.DSError, Source: "compile", Message: "variable $y not found",
+		},
+	}))
+}
+
+func TestDidChangeDiagnostics_IncrementalCRLF(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams("var x\r\necho $x\r\n"))
+	checkDiag(t, f, diagParam([]lsp.Diagnostic{}))
+
+	f.conn.Notify(bgCtx, "textDocument/didChange", didChangeTextDocumentParams{
+		TextDocument: lsp.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: testURI},
+		},
+		ContentChanges: []contentChangeEvent{
+			// var x\r\necho $x\r\n -> var x\r\nvar y\r\necho $x\r\n
+			{Range: lspRange(1, 0, 1, 0), Text: "var y\r\n"},
+			// Characters past the end of a line are clamped to the line end.
+			// var x\r\nvar y\r\necho $x\r\n -> var x\r\nvar y\r\necho $x $y $z\r\n
+			{Range: lspRange(2, 7, 2, 100), Text: " $y $z"},
+			// Delete the line break before the third line.
+			// var x\r\nvar y\r\necho $x $y $z\r\n -> var x\r\nvar y; echo $x $y $z\r\n
+			{Range: lspRange(1, 5, 2, 0), Text: "; "},
+		},
+	})
+	checkDiag(t, f, diagParam([]lsp.Diagnostic{
+		{
+			Range:    *lspRange(1, 18, 1, 20),
+			Severity: lsp.DSError, Source: "compile", Message: "variable $z not found",
+		},
+	}))
+}
+
 var hoverTests = []struct {
 	name string
 	text string
@@ -201,6 +303,559 @@ func TestCompletion(t *testing.T) {
 	}
 }
 
+var definitionTests = []struct {
+	name string
+	text string
+	pos  lsp.Position
+
+	wantRange *lsp.Range
+}{
+	{
+		name: "variable",
+		text: "var x = foo\necho $x",
+		pos:  lsp.Position{Line: 1, Character: 6},
+
+		wantRange: lspRange(0, 4, 0, 5),
+	},
+	{
+		name: "function",
+		text: "fn f { }\nf",
+		pos:  lsp.Position{Line: 1, Character: 0},
+
+		wantRange: lspRange(0, 3, 0, 4),
+	},
+	{
+		name: "shadowing variable in lambda",
+		//                 0123456789012345
+		text: "var x\n{ var x; echo $x }",
+		pos:  lsp.Position{Line: 1, Character: 15},
+
+		wantRange: lspRange(1, 6, 1, 7),
+	},
+	{
+		name: "lambda parameter",
+		//     0123456789012
+		text: "{|a| echo $a }",
+		pos:  lsp.Position{Line: 0, Character: 11},
+
+		wantRange: lspRange(0, 2, 0, 3),
+	},
+	{
+		name: "definition site",
+		text: "var x",
+		pos:  lsp.Position{Line: 0, Character: 4},
+
+		wantRange: lspRange(0, 4, 0, 5),
+	},
+	{
+		name: "builtin",
+		text: "echo $paths",
+		pos:  lsp.Position{Line: 0, Character: 6},
+
+		wantRange: nil,
+	},
+}
+
+func TestDefinition(t *testing.T) {
+	f := setup(t)
+
+	for _, test := range definitionTests {
+		t.Run(test.name, func(t *testing.T) {
+			f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(test.text))
+			var response *lsp.Location
+			err := f.conn.Call(bgCtx, "textDocument/definition", positionParams(test.pos), &response)
+			if err != nil {
+				t.Errorf("got error %v", err)
+			}
+			var want *lsp.Location
+			if test.wantRange != nil {
+				want = &lsp.Location{URI: testURI, Range: *test.wantRange}
+			}
+			if diff := cmp.Diff(want, response); diff != "" {
+				t.Errorf("response (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDefinition_Module(t *testing.T) {
+	modURI := setupLibModule(t, "fn f { }\nvar v = 1")
+	f := setup(t)
+
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams("use mod\nmod:f\necho $mod:v"))
+	for _, test := range []struct {
+		pos       lsp.Position
+		wantRange lsp.Range
+	}{
+		{lsp.Position{Line: 1, Character: 4}, *lspRange(0, 3, 0, 4)},
+		{lsp.Position{Line: 2, Character: 10}, *lspRange(1, 4, 1, 5)},
+	} {
+		var response lsp.Location
+		err := f.conn.Call(bgCtx, "textDocument/definition", positionParams(test.pos), &response)
+		if err != nil {
+			t.Errorf("got error %v", err)
+		}
+		want := lsp.Location{URI: modURI, Range: test.wantRange}
+		if diff := cmp.Diff(want, response); diff != "" {
+			t.Errorf("response (-want +got):\n%s", diff)
+		}
+	}
+}
+
+var referencesTests = []struct {
+	name        string
+	text        string
+	pos         lsp.Position
+	includeDecl bool
+
+	wantRanges []lsp.Range
+}{
+	{
+		name: "with declaration",
+		//                  0123456789012345
+		text: "var x\necho $x\n{ var x; echo $x }\necho $x",
+		pos:  lsp.Position{Line: 0, Character: 4}, includeDecl: true,
+
+		wantRanges: []lsp.Range{*lspRange(0, 4, 0, 5), *lspRange(1, 6, 1, 7), *lspRange(3, 6, 3, 7)},
+	},
+	{
+		name: "without declaration",
+		text: "var x\necho $x\n{ var x; echo $x }\necho $x",
+		pos:  lsp.Position{Line: 1, Character: 6},
+
+		wantRanges: []lsp.Range{*lspRange(1, 6, 1, 7), *lspRange(3, 6, 3, 7)},
+	},
+	{
+		name: "function",
+		text: "fn f { f }\nf",
+		pos:  lsp.Position{Line: 1, Character: 0}, includeDecl: true,
+
+		wantRanges: []lsp.Range{*lspRange(0, 3, 0, 4), *lspRange(0, 7, 0, 8), *lspRange(1, 0, 1, 1)},
+	},
+	{
+		name: "function variable",
+		//                 0123456789
+		text: "fn f { }\necho $f~",
+		pos:  lsp.Position{Line: 1, Character: 7}, includeDecl: true,
+
+		wantRanges: []lsp.Range{*lspRange(0, 3, 0, 4), *lspRange(1, 6, 1, 7)},
+	},
+	{
+		name: "namespace variable",
+		//                 0123456789
+		text: "use str\necho $str:",
+		pos:  lsp.Position{Line: 1, Character: 6}, includeDecl: true,
+
+		wantRanges: []lsp.Range{*lspRange(0, 4, 0, 7), *lspRange(1, 6, 1, 9)},
+	},
+	{
+		name: "namespace with path",
+		//     0123456789012345678
+		text: "use a/a\necho $a:",
+		pos:  lsp.Position{Line: 1, Character: 6}, includeDecl: true,
+
+		wantRanges: []lsp.Range{*lspRange(0, 6, 0, 7), *lspRange(1, 6, 1, 7)},
+	},
+	{
+		name: "builtin",
+		text: "echo $paths",
+		pos:  lsp.Position{Line: 0, Character: 6},
+
+		wantRanges: []lsp.Range{},
+	},
+}
+
+func TestReferences(t *testing.T) {
+	f := setup(t)
+
+	for _, test := range referencesTests {
+		t.Run(test.name, func(t *testing.T) {
+			f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(test.text))
+			var response []lsp.Location
+			err := f.conn.Call(bgCtx, "textDocument/references",
+				referencesParams(test.pos, test.includeDecl), &response)
+			if err != nil {
+				t.Errorf("got error %v", err)
+			}
+			want := make([]lsp.Location, len(test.wantRanges))
+			for i, r := range test.wantRanges {
+				want[i] = lsp.Location{URI: testURI, Range: r}
+			}
+			if diff := cmp.Diff(want, response); diff != "" {
+				t.Errorf("response (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReferences_Module(t *testing.T) {
+	modURI := setupLibModule(t, "fn f { }")
+	f := setup(t)
+
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams("use mod\nmod:f; mod:f"))
+	var response []lsp.Location
+	err := f.conn.Call(bgCtx, "textDocument/references",
+		referencesParams(lsp.Position{Line: 1, Character: 4}, true), &response)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	want := []lsp.Location{
+		{URI: modURI, Range: *lspRange(0, 3, 0, 4)},
+		{URI: testURI, Range: *lspRange(1, 4, 1, 5)},
+		{URI: testURI, Range: *lspRange(1, 11, 1, 12)},
+	}
+	if diff := cmp.Diff(want, response); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+}
+
+// Creates a module named mod in a library directory, and returns its URI.
+func setupLibModule(t *testing.T, code string) lsp.DocumentURI {
+	configHome := testutil.TempDir(t)
+	testutil.Setenv(t, env.XDG_CONFIG_HOME, configHome)
+	testutil.ApplyDirIn(testutil.Dir{
+		"elvish": testutil.Dir{"lib": testutil.Dir{"mod.elv": code}}}, configHome)
+	return pathToURI(filepath.Join(configHome, "elvish", "lib", "mod.elv"))
+}
+
+var renameTests = []struct {
+	name    string
+	text    string
+	pos     lsp.Position
+	newName string
+
+	wantRanges []lsp.Range
+}{
+	{
+		name: "variable with shadowing",
+		//                  0123456789012345
+		text: "var x\necho $x\n{ var x; echo $x }\necho $x",
+		pos:  lsp.Position{Line: 1, Character: 6}, newName: "y",
+
+		wantRanges: []lsp.Range{*lspRange(0, 4, 0, 5), *lspRange(1, 6, 1, 7), *lspRange(3, 6, 3, 7)},
+	},
+	{
+		name: "function",
+		text: "fn f { f }\nf",
+		pos:  lsp.Position{Line: 0, Character: 3}, newName: "g",
+
+		wantRanges: []lsp.Range{*lspRange(0, 3, 0, 4), *lspRange(0, 7, 0, 8), *lspRange(1, 0, 1, 1)},
+	},
+	{
+		name: "namespace with alias",
+		text: "use str s\necho $s:",
+		pos:  lsp.Position{Line: 1, Character: 6}, newName: "t",
+
+		wantRanges: []lsp.Range{*lspRange(0, 8, 0, 9), *lspRange(1, 6, 1, 7)},
+	},
+}
+
+func TestRename(t *testing.T) {
+	f := setup(t)
+
+	for _, test := range renameTests {
+		t.Run(test.name, func(t *testing.T) {
+			f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(test.text))
+			var response workspaceEdit
+			err := f.conn.Call(bgCtx, "textDocument/rename",
+				renameParams{positionParams(test.pos), test.newName}, &response)
+			if err != nil {
+				t.Errorf("got error %v", err)
+			}
+			want := workspaceEdit{Changes: map[lsp.DocumentURI][]lsp.TextEdit{testURI: {}}}
+			for _, r := range test.wantRanges {
+				want.Changes[testURI] = append(want.Changes[testURI],
+					lsp.TextEdit{Range: r, NewText: test.newName})
+			}
+			if diff := cmp.Diff(want, response); diff != "" {
+				t.Errorf("response (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRename_NamespaceWithoutAlias(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams("use a/str\necho $str:"))
+	var response workspaceEdit
+	err := f.conn.Call(bgCtx, "textDocument/rename",
+		renameParams{positionParams(lsp.Position{Line: 1, Character: 6}), "s"}, &response)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	// The module spec is kept, and an alias is added after it.
+	want := workspaceEdit{Changes: map[lsp.DocumentURI][]lsp.TextEdit{testURI: {
+		{Range: *lspRange(0, 9, 0, 9), NewText: " s"},
+		{Range: *lspRange(1, 6, 1, 9), NewText: "s"},
+	}}}
+	if diff := cmp.Diff(want, response); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+}
+
+var renameErrorTests = []struct {
+	name    string
+	text    string
+	newName string
+	wantErr string
+}{
+	{"builtin", "echo $paths", "y", "can only rename variables and functions defined in Elvish source"},
+	{"invalid name", "var x; echo $x", "a b", "invalid name: 'a b'"},
+	{"qualified name", "var x; echo $x", "a:b", "invalid name: a:b"},
+}
+
+func TestRename_Errors(t *testing.T) {
+	f := setup(t)
+
+	for _, test := range renameErrorTests {
+		t.Run(test.name, func(t *testing.T) {
+			f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(test.text))
+			err := f.conn.Call(bgCtx, "textDocument/rename",
+				renameParams{positionParams(lsp.Position{Line: 0, Character: 13}), test.newName},
+				&workspaceEdit{})
+			if err == nil || err.(*jsonrpc2.Error).Message != test.wantErr {
+				t.Errorf("got error %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+var signatureHelpTests = []struct {
+	name string
+	text string
+	pos  lsp.Position
+
+	wantLabel  string
+	wantParams []string
+	wantActive int
+	wantDoc    bool
+}{
+	{
+		name: "closure, first argument",
+		//                             0123456
+		text: "fn f {|a b &opt=x| }\nf 1 2",
+		pos:  lsp.Position{Line: 1, Character: 2},
+
+		wantLabel:  "f a b &opt=x",
+		wantParams: []string{"a", "b", "&opt=x"},
+		wantActive: 0,
+	},
+	{
+		name: "closure, second argument",
+		text: "fn f {|a b &opt=x| }\nf 1 2",
+		pos:  lsp.Position{Line: 1, Character: 5},
+
+		wantLabel:  "f a b &opt=x",
+		wantParams: []string{"a", "b", "&opt=x"},
+		wantActive: 1,
+	},
+	{
+		name: "closure, option",
+		text: "fn f {|a b &opt=x| }\nf &opt=y",
+		pos:  lsp.Position{Line: 1, Character: 4},
+
+		wantLabel:  "f a b &opt=x",
+		wantParams: []string{"a", "b", "&opt=x"},
+		wantActive: 2,
+	},
+	{
+		name: "closure, too many arguments",
+		text: "fn f {|a| }\nf 1 2",
+		pos:  lsp.Position{Line: 1, Character: 5},
+
+		wantLabel:  "f a",
+		wantParams: []string{"a"},
+		wantActive: 1,
+	},
+	{
+		name: "builtin with rest argument",
+		//     01234567890
+		text: "echo a b c",
+		pos:  lsp.Position{Line: 0, Character: 9},
+
+		wantLabel:  "echo &sep=' ' @value",
+		wantParams: []string{"&sep=' '", "@value"},
+		wantActive: 1,
+		wantDoc:    true,
+	},
+}
+
+func TestSignatureHelp(t *testing.T) {
+	f := setup(t)
+
+	for _, test := range signatureHelpTests {
+		t.Run(test.name, func(t *testing.T) {
+			f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(test.text))
+			var response signatureHelp
+			err := f.conn.Call(bgCtx, "textDocument/signatureHelp", positionParams(test.pos), &response)
+			if err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if len(response.Signatures) != 1 {
+				t.Fatalf("got %d signatures, want 1", len(response.Signatures))
+			}
+			sig := response.Signatures[0]
+			if sig.Label != test.wantLabel {
+				t.Errorf("got label %q, want %q", sig.Label, test.wantLabel)
+			}
+			var params []string
+			for _, param := range sig.Parameters {
+				params = append(params, param.Label)
+			}
+			if diff := cmp.Diff(test.wantParams, params); diff != "" {
+				t.Errorf("parameters (-want +got):\n%s", diff)
+			}
+			if response.ActiveParameter != test.wantActive {
+				t.Errorf("got active parameter %d, want %d", response.ActiveParameter, test.wantActive)
+			}
+			if (sig.Documentation != nil) != test.wantDoc {
+				t.Errorf("got documentation %v, want documentation: %v", sig.Documentation, test.wantDoc)
+			}
+		})
+	}
+}
+
+func TestSignatureHelp_NoSignature(t *testing.T) {
+	f := setup(t)
+
+	for _, test := range []struct {
+		text string
+		char int
+	}{
+		{"some-external a", 15},
+		{"echo", 2},
+		{"echo {  }", 7},
+	} {
+		f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(test.text))
+		var response *signatureHelp
+		pos := lsp.Position{Line: 0, Character: test.char}
+		err := f.conn.Call(bgCtx, "textDocument/signatureHelp", positionParams(pos), &response)
+		if err != nil {
+			t.Errorf("got error %v", err)
+		}
+		if response != nil {
+			t.Errorf("%q: got %v, want nil", test.text, response)
+		}
+	}
+}
+
+var formattingTests = []struct {
+	name string
+	text string
+	want []lsp.TextEdit
+}{
+	{"unformatted", "echo  a|each {|x|put $x}\n\n\n",
+		[]lsp.TextEdit{{Range: *lspRange(0, 0, 3, 0), NewText: "echo a | each {|x| put $x }\n"}}},
+	{"already formatted", "echo a\n", []lsp.TextEdit{}},
+	{"parse error", "echo [", []lsp.TextEdit{}},
+}
+
+func TestFormatting(t *testing.T) {
+	f := setup(t)
+
+	for _, test := range formattingTests {
+		t.Run(test.name, func(t *testing.T) {
+			f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(test.text))
+			var response []lsp.TextEdit
+			err := f.conn.Call(bgCtx, "textDocument/formatting",
+				documentFormattingParams{TextDocument: lsp.TextDocumentIdentifier{URI: testURI}}, &response)
+			if err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if diff := cmp.Diff(test.want, response); diff != "" {
+				t.Errorf("response (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocumentSymbol(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams("var x\nfn f {|a|\n  var y\n}\nuse a/str"))
+	var response []documentSymbol
+	err := f.conn.Call(bgCtx, "textDocument/documentSymbol",
+		documentSymbolParams{TextDocument: lsp.TextDocumentIdentifier{URI: testURI}}, &response)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	want := []documentSymbol{
+		{Name: "x", Kind: symbolKindVariable,
+			Range: *lspRange(0, 0, 0, 5), SelectionRange: *lspRange(0, 4, 0, 5)},
+		{Name: "f", Kind: symbolKindFunction,
+			Range: *lspRange(1, 0, 3, 1), SelectionRange: *lspRange(1, 3, 1, 4),
+			Children: []documentSymbol{
+				{Name: "y", Kind: symbolKindVariable,
+					Range: *lspRange(2, 2, 2, 7), SelectionRange: *lspRange(2, 6, 2, 7)},
+			}},
+		{Name: "str", Kind: symbolKindNamespace,
+			Range: *lspRange(4, 0, 4, 9), SelectionRange: *lspRange(4, 6, 4, 9)},
+	}
+	if diff := cmp.Diff(want, response); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+}
+
+func TestWorkspaceSymbol(t *testing.T) {
+	root := testutil.TempDir(t)
+	testutil.ApplyDirIn(testutil.Dir{
+		"a.elv":   "fn foo {\n  var bar\n}",
+		"sub":     testutil.Dir{"b.elv": "var foobar"},
+		".hidden": testutil.Dir{"c.elv": "var foo-hidden"},
+		"d.txt":   "var foo-txt",
+		"vendor":  testutil.Dir{"e.elv": "var foo-vendor"},
+	}, root)
+	f := setupWithParams(t, initializeParams{RootURI: pathToURI(root)})
+	aURI := pathToURI(filepath.Join(root, "a.elv"))
+	bURI := pathToURI(filepath.Join(root, "sub", "b.elv"))
+
+	query := func(q string) []symbolInformation {
+		t.Helper()
+		var response []symbolInformation
+		err := f.conn.Call(bgCtx, "workspace/symbol", workspaceSymbolParams{Query: q}, &response)
+		if err != nil {
+			t.Errorf("got error %v", err)
+		}
+		return response
+	}
+
+	// The workspace is indexed in the background; wait until it's done.
+	deadline := time.Now().Add(testutil.Scaled(time.Second))
+	for len(query("foo")) < 2 && time.Now().Before(deadline) {
+		time.Sleep(testutil.Scaled(time.Millisecond))
+	}
+
+	want := []symbolInformation{
+		{Name: "foo", Kind: symbolKindFunction,
+			Location: lsp.Location{URI: aURI, Range: *lspRange(0, 3, 0, 6)}},
+		{Name: "foobar", Kind: symbolKindVariable,
+			Location: lsp.Location{URI: bURI, Range: *lspRange(0, 4, 0, 10)}},
+	}
+	if diff := cmp.Diff(want, query("FOO")); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+	want = []symbolInformation{
+		{Name: "bar", Kind: symbolKindVariable,
+			Location:      lsp.Location{URI: aURI, Range: *lspRange(1, 6, 1, 9)},
+			ContainerName: "foo"},
+		{Name: "foobar", Kind: symbolKindVariable,
+			Location: lsp.Location{URI: bURI, Range: *lspRange(0, 4, 0, 10)}},
+	}
+	if diff := cmp.Diff(want, query("bar")); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+
+	// Edits in the client are reflected in the index.
+	f.conn.Notify(bgCtx, "textDocument/didOpen", lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{URI: bURI, Text: "var quux"}})
+	want = []symbolInformation{
+		{Name: "quux", Kind: symbolKindVariable,
+			Location: lsp.Location{URI: bURI, Range: *lspRange(0, 4, 0, 8)}},
+	}
+	if diff := cmp.Diff(want, query("quux")); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+}
+
 var jsonrpcErrorTests = []struct {
 	name    string
 	method  string
@@ -225,6 +880,20 @@ var jsonrpcErrorTests = []struct {
 		},
 		unknownDocument("file://unknown"),
 	},
+	{
+		"unknown document to definition", "textDocument/definition",
+		lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: "file://unknown"},
+		},
+		unknownDocument("file://unknown"),
+	},
+	{
+		"unknown document to references", "textDocument/references",
+		lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: "file://unknown"},
+		},
+		unknownDocument("file://unknown"),
+	},
 }
 
 func TestJSONRPCErrors(t *testing.T) {
@@ -274,6 +943,26 @@ func checkDiag(t *testing.T, f *clientFixture, want lsp.PublishDiagnosticsParams
 	}
 }
 
+func positionParams(pos lsp.Position) lsp.TextDocumentPositionParams {
+	return lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: testURI},
+		Position:     pos,
+	}
+}
+
+func referencesParams(pos lsp.Position, includeDecl bool) referenceParams {
+	params := referenceParams{TextDocumentPositionParams: positionParams(pos)}
+	params.Context.IncludeDeclaration = includeDecl
+	return params
+}
+
+func lspRange(startLine, startChar, endLine, endChar int) *lsp.Range {
+	return &lsp.Range{
+		Start: lsp.Position{Line: startLine, Character: startChar},
+		End:   lsp.Position{Line: endLine, Character: endChar},
+	}
+}
+
 func completionParams(line, char int) lsp.CompletionParams {
 	return lsp.CompletionParams{
 		TextDocumentPositionParams: lsp.TextDocumentPositionParams{
@@ -289,6 +978,10 @@ type clientFixture struct {
 }
 
 func setup(t *testing.T) *clientFixture {
+	return setupWithParams(t, initializeParams{})
+}
+
+func setupWithParams(t *testing.T, params initializeParams) *clientFixture {
 	r0, w0 := must.Pipe()
 	r1, w1 := must.Pipe()
 
@@ -310,7 +1003,7 @@ func setup(t *testing.T) *clientFixture {
 
 	// LSP handshake
 	err := conn.Call(context.Background(),
-		"initialize", lsp.InitializeParams{}, &lsp.InitializeResult{})
+		"initialize", params, &lsp.InitializeResult{})
 	if err != nil {
 		t.Errorf("got error %v, want nil", err)
 	}
@@ -4,11 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/sourcegraph/jsonrpc2"
 	lsp "pkg.nimblebun.works/go-lsp"
 	"src.elv.sh/pkg/diag"
 	"src.elv.sh/pkg/edit/complete"
+	"src.elv.sh/pkg/elvfmt"
 	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/mods/doc"
 	"src.elv.sh/pkg/parse"
@@ -28,6 +33,23 @@ var (
 type server struct {
 	evaler    *eval.Evaler
 	documents map[lsp.DocumentURI]document
+
+	// Protects symbols and files, which are also updated by the goroutine
+	// indexing the workspace.
+	mutex sync.Mutex
+	// Symbols defined in open documents and .elv files in the workspace.
+	symbols map[lsp.DocumentURI][]symbolInformation
+	// Documents read from disk, kept to avoid parsing them again until they
+	// are modified.
+	files map[lsp.DocumentURI]file
+}
+
+// file is a document read from disk, along with the modification time and
+// size of the file when it was read.
+type file struct {
+	modTime time.Time
+	size    int64
+	doc     document
 }
 
 type document struct {
@@ -46,7 +68,7 @@ func isVariableShadowed(parseTree parse.Tree, pos int, varName string) bool {
 
 	// Check if this variable is defined locally
 	found := false
-	eachDefinedVariableAtPos(path[len(path)-1], path[0].Range().From, func(name string) {
+	eachDefinedVariableAtPos(path[len(path)-1], path[0].Range().From, func(name string, _ *parse.Compound) {
 		if name == varName {
 			found = true
 		}
@@ -54,9 +76,10 @@ func isVariableShadowed(parseTree parse.Tree, pos int, varName string) bool {
 	return found
 }
 
-// eachDefinedVariableAtPos calls f for each variable defined and visible at pos
+// eachDefinedVariableAtPos calls f for each variable defined and visible at pos,
+// along with the node that names the variable at its definition site.
 // This is adapted from pkg/edit/complete/ns_helper.go:eachDefinedVariable
-func eachDefinedVariableAtPos(n parse.Node, pos int, f func(string)) {
+func eachDefinedVariableAtPos(n parse.Node, pos int, f func(string, *parse.Compound)) {
 	if fn, ok := n.(*parse.Form); ok {
 		eachDefinedVariableInForm(fn, f)
 	}
@@ -64,7 +87,7 @@ func eachDefinedVariableAtPos(n parse.Node, pos int, f func(string)) {
 		for _, param := range pn.Elements {
 			if varRef, ok := cmpd.StringLiteral(param); ok {
 				_, name := eval.SplitSigil(varRef)
-				f(name)
+				f(name, param)
 			}
 		}
 	}
@@ -81,9 +104,11 @@ func eachDefinedVariableAtPos(n parse.Node, pos int, f func(string)) {
 	}
 }
 
-// eachDefinedVariableInForm calls f for each variable defined in fn
+// eachDefinedVariableInForm calls f for each variable defined in fn, along with
+// the node that names the variable. Namespaces imported with use are reported
+// with a trailing eval.NsSuffix.
 // This is adapted from pkg/edit/complete/ns_helper.go:eachDefinedVariableInForm
-func eachDefinedVariableInForm(fn *parse.Form, f func(string)) {
+func eachDefinedVariableInForm(fn *parse.Form, f func(string, *parse.Compound)) {
 	if fn.Head == nil {
 		return
 	}
@@ -95,13 +120,26 @@ func eachDefinedVariableInForm(fn *parse.Form, f func(string)) {
 			}
 			if varRef, ok := cmpd.StringLiteral(arg); ok {
 				_, name := eval.SplitSigil(varRef)
-				f(name)
+				f(name, arg)
 			}
 		}
 	case "fn":
 		if len(fn.Args) >= 1 {
 			if name, ok := cmpd.StringLiteral(fn.Args[0]); ok {
-				f(name + eval.FnSuffix)
+				f(name+eval.FnSuffix, fn.Args[0])
+			}
+		}
+	case "use":
+		// Mirrors compileUse in pkg/eval: the namespace is named after the
+		// optional second argument, or the last component of the spec.
+		switch len(fn.Args) {
+		case 1:
+			if spec, ok := cmpd.StringLiteral(fn.Args[0]); ok {
+				f(spec[strings.LastIndexByte(spec, '/')+1:]+eval.NsSuffix, fn.Args[0])
+			}
+		case 2:
+			if name, ok := cmpd.StringLiteral(fn.Args[1]); ok {
+				f(name+eval.NsSuffix, fn.Args[1])
 			}
 		}
 	}
@@ -115,23 +153,35 @@ func getLocalVariableInfo(varName string) (string, error) {
 }
 
 func newServer() *server {
-	return &server{eval.NewEvaler(), make(map[lsp.DocumentURI]document)}
+	return &server{
+		evaler:    eval.NewEvaler(),
+		documents: make(map[lsp.DocumentURI]document),
+		symbols:   make(map[lsp.DocumentURI][]symbolInformation),
+		files:     make(map[lsp.DocumentURI]file),
+	}
 }
 
 func handler(s *server) jsonrpc2.Handler {
 	return routingHandler(map[string]method{
-		"initialize":              s.initialize,
-		"textDocument/didOpen":    convertMethod(s.didOpen),
-		"textDocument/didChange":  convertMethod(s.didChange),
-		"textDocument/hover":      convertMethod(s.hover),
-		"textDocument/completion": convertMethod(s.completion),
+		"initialize":                  convertMethod(s.initialize),
+		"textDocument/didOpen":        convertMethod(s.didOpen),
+		"textDocument/didChange":      convertMethod(s.didChange),
+		"textDocument/didClose":       convertMethod(s.didClose),
+		"textDocument/hover":          convertMethod(s.hover),
+		"textDocument/completion":     convertMethod(s.completion),
+		"textDocument/definition":     convertMethod(s.definition),
+		"textDocument/references":     convertMethod(s.references),
+		"textDocument/rename":         convertMethod(s.rename),
+		"textDocument/signatureHelp":  convertMethod(s.signatureHelp),
+		"textDocument/formatting":     convertMethod(s.formatting),
+		"textDocument/documentSymbol": convertMethod(s.documentSymbol),
+		"workspace/symbol":            convertMethod(s.workspaceSymbol),
+		// Called by clients even when server doesn't advertise support:
+		// https://microsoft.github.io/language-server-protocol/specification#workspace_didChangeWatchedFiles
+		"workspace/didChangeWatchedFiles": convertMethod(s.didChangeWatchedFiles),
 
-		"textDocument/didClose": noop,
 		// Required by spec.
 		"initialized": noop,
-		// Called by clients even when server doesn't advertise support:
-		// https://microsoft.github.io/language-server-protocol/specification#workspace_didChangeWatchedFiles
-		"workspace/didChangeWatchedFiles": noop,
 	})
 }
 
@@ -167,15 +217,55 @@ func conn(ctx context.Context) *jsonrpc2.Conn { return ctx.Value(connKey{}).(*js
 
 // Handler implementations. These are all called synchronously.
 
-func (s *server) initialize(_ context.Context, _ json.RawMessage) (any, error) {
-	return &lsp.InitializeResult{
-		Capabilities: lsp.ServerCapabilities{
-			TextDocumentSync: &lsp.TextDocumentSyncOptions{
-				OpenClose: true,
-				Change:    lsp.TDSyncKindFull,
+// initializeResult mirrors lsp.InitializeResult, using serverCapabilities.
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+}
+
+// serverCapabilities extends lsp.ServerCapabilities with more providers. Most of
+// them are declared as plain booleans, which the protocol accepts in place of
+// option objects.
+type serverCapabilities struct {
+	lsp.ServerCapabilities
+	DefinitionProvider         bool                  `json:"definitionProvider,omitempty"`
+	ReferencesProvider         bool                  `json:"referencesProvider,omitempty"`
+	DocumentSymbolProvider     bool                  `json:"documentSymbolProvider,omitempty"`
+	WorkspaceSymbolProvider    bool                  `json:"workspaceSymbolProvider,omitempty"`
+	RenameProvider             bool                  `json:"renameProvider,omitempty"`
+	SignatureHelpProvider      *signatureHelpOptions `json:"signatureHelpProvider,omitempty"`
+	DocumentFormattingProvider bool                  `json:"documentFormattingProvider,omitempty"`
+}
+
+type signatureHelpOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+}
+
+type initializeParams struct {
+	RootURI  lsp.DocumentURI `json:"rootUri"`
+	RootPath string          `json:"rootPath"`
+}
+
+func (s *server) initialize(_ context.Context, params initializeParams) (any, error) {
+	if root, ok := rootPath(params); ok {
+		go s.indexWorkspace(root)
+	}
+	return &initializeResult{
+		Capabilities: serverCapabilities{
+			ServerCapabilities: lsp.ServerCapabilities{
+				TextDocumentSync: &lsp.TextDocumentSyncOptions{
+					OpenClose: true,
+					Change:    lsp.TDSyncKindIncremental,
+				},
+				CompletionProvider: &lsp.CompletionOptions{},
+				HoverProvider:      &lsp.HoverOptions{},
 			},
-			CompletionProvider: &lsp.CompletionOptions{},
-			HoverProvider:      &lsp.HoverOptions{},
+			DefinitionProvider:         true,
+			ReferencesProvider:         true,
+			DocumentSymbolProvider:     true,
+			WorkspaceSymbolProvider:    true,
+			RenameProvider:             true,
+			SignatureHelpProvider:      &signatureHelpOptions{TriggerCharacters: []string{" "}},
+			DocumentFormattingProvider: true,
 		},
 	}, nil
 }
@@ -186,11 +276,79 @@ func (s *server) didOpen(ctx context.Context, params lsp.DidOpenTextDocumentPara
 	return nil, nil
 }
 
-func (s *server) didChange(ctx context.Context, params lsp.DidChangeTextDocumentParams) (any, error) {
-	// ContentChanges includes full text since the server is only advertised to
-	// support that; see the initialize method.
-	uri, content := params.TextDocument.URI, params.ContentChanges[0].Text
-	s.updateDocument(conn(ctx), uri, content)
+// Like lsp.DidChangeTextDocumentParams, but with the range of each change,
+// which is needed to support incremental sync.
+type didChangeTextDocumentParams struct {
+	TextDocument   lsp.VersionedTextDocumentIdentifier `json:"textDocument"`
+	ContentChanges []contentChangeEvent                `json:"contentChanges"`
+}
+
+type contentChangeEvent struct {
+	// The range of the document that is replaced by Text. The entire document
+	// is replaced if Range is nil.
+	Range *lsp.Range `json:"range,omitempty"`
+	Text  string     `json:"text"`
+}
+
+func (s *server) didChange(ctx context.Context, params didChangeTextDocumentParams) (any, error) {
+	uri := params.TextDocument.URI
+	document, ok := s.documents[uri]
+	if !ok {
+		return nil, unknownDocument(uri)
+	}
+	code := document.code
+	for _, change := range params.ContentChanges {
+		code = applyChange(code, change)
+	}
+	s.updateDocument(conn(ctx), uri, code)
+	return nil, nil
+}
+
+// Applies a change to code. Changes are applied in order, and the range of
+// each change refers to the code after applying the previous changes.
+func applyChange(code string, change contentChangeEvent) string {
+	if change.Range == nil {
+		return change.Text
+	}
+	from := lspPositionToIdx(code, change.Range.Start)
+	to := lspPositionToIdx(code, change.Range.End)
+	return code[:from] + change.Text + code[to:]
+}
+
+type didCloseParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+}
+
+func (s *server) didClose(_ context.Context, params didCloseParams) (any, error) {
+	uri := params.TextDocument.URI
+	delete(s.documents, uri)
+	// The file may still be part of the workspace; index it from disk again.
+	s.indexFile(uri)
+	return nil, nil
+}
+
+type didChangeWatchedFilesParams struct {
+	Changes []struct {
+		URI  lsp.DocumentURI `json:"uri"`
+		Type int             `json:"type"`
+	} `json:"changes"`
+}
+
+// The value of the type field of a file event for deleted files.
+const fileChangeTypeDeleted = 3
+
+func (s *server) didChangeWatchedFiles(_ context.Context, params didChangeWatchedFilesParams) (any, error) {
+	for _, change := range params.Changes {
+		if _, open := s.documents[change.URI]; open {
+			// The index is kept up to date with the content in the client.
+			continue
+		}
+		if change.Type == fileChangeTypeDeleted {
+			s.unindex(change.URI)
+		} else if strings.HasSuffix(string(change.URI), ".elv") {
+			s.indexFile(change.URI)
+		}
+	}
 	return nil, nil
 }
 
@@ -327,26 +485,204 @@ func (s *server) completion(_ context.Context, params lsp.CompletionParams) (any
 	return lspItems, nil
 }
 
+func (s *server) definition(_ context.Context, params lsp.TextDocumentPositionParams) (any, error) {
+	uri := params.TextDocument.URI
+	document, ok := s.documents[uri]
+	if !ok {
+		return nil, unknownDocument(uri)
+	}
+	ref, ok := referenceAt(document.parseTree.Root, lspPositionToIdx(document.code, params.Position))
+	if !ok {
+		return nil, nil
+	}
+	def := s.resolve(uri, document, ref)
+	if def == nil {
+		return nil, nil
+	}
+	defDocument, ok := s.loadDocument(def.uri)
+	if !ok {
+		return nil, nil
+	}
+	return lsp.Location{
+		URI:   def.uri,
+		Range: lspRangeFromRange(defDocument.code, newReference(def.name, def.node).nameRange),
+	}, nil
+}
+
+type referenceParams struct {
+	lsp.TextDocumentPositionParams
+	Context struct {
+		IncludeDeclaration bool `json:"includeDeclaration"`
+	} `json:"context"`
+}
+
+func (s *server) references(_ context.Context, params referenceParams) (any, error) {
+	uri := params.TextDocument.URI
+	document, ok := s.documents[uri]
+	if !ok {
+		return nil, unknownDocument(uri)
+	}
+	ref, ok := referenceAt(document.parseTree.Root, lspPositionToIdx(document.code, params.Position))
+	if !ok {
+		return []lsp.Location{}, nil
+	}
+	def := s.resolve(uri, document, ref)
+	if def == nil {
+		return []lsp.Location{}, nil
+	}
+	return s.findReferences(def, params.Context.IncludeDeclaration), nil
+}
+
+type renameParams struct {
+	lsp.TextDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+type workspaceEdit struct {
+	Changes map[lsp.DocumentURI][]lsp.TextEdit `json:"changes"`
+}
+
+func (s *server) rename(_ context.Context, params renameParams) (any, error) {
+	uri := params.TextDocument.URI
+	document, ok := s.documents[uri]
+	if !ok {
+		return nil, unknownDocument(uri)
+	}
+	if !isValidVariableName(params.NewName) {
+		return nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeInvalidParams,
+			Message: fmt.Sprintf("invalid name: %s", parse.Quote(params.NewName)),
+		}
+	}
+	ref, ok := referenceAt(document.parseTree.Root, lspPositionToIdx(document.code, params.Position))
+	if !ok {
+		return nil, nil
+	}
+	def := s.resolve(uri, document, ref)
+	if def == nil {
+		return nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeInvalidParams,
+			Message: "can only rename variables and functions defined in Elvish source",
+		}
+	}
+	// A namespace imported with "use" without an alias is named after its
+	// module spec, which must not be changed; add an alias after it instead.
+	var specRange, aliasRange lsp.Range
+	addAlias := false
+	if useForm, ok := parse.Parent(def.node).(*parse.Form); ok &&
+		strings.HasSuffix(def.name, eval.NsSuffix) && len(useForm.Args) == 1 {
+		if defDoc, ok := s.loadDocument(def.uri); ok {
+			specRange = lspRangeFromRange(defDoc.code, newReference(def.name, def.node).nameRange)
+			end := def.node.Range().To
+			aliasRange = lspRangeFromRange(defDoc.code, diag.Ranging{From: end, To: end})
+			addAlias = true
+		}
+	}
+	edit := workspaceEdit{Changes: make(map[lsp.DocumentURI][]lsp.TextEdit)}
+	for _, loc := range s.findReferences(def, true) {
+		textEdit := lsp.TextEdit{Range: loc.Range, NewText: params.NewName}
+		if addAlias && loc.URI == def.uri && loc.Range == specRange {
+			textEdit = lsp.TextEdit{Range: aliasRange, NewText: " " + params.NewName}
+		}
+		edit.Changes[loc.URI] = append(edit.Changes[loc.URI], textEdit)
+	}
+	return edit, nil
+}
+
+// Reports whether name can be used as the unqualified name of a variable or
+// function without quoting.
+func isValidVariableName(name string) bool {
+	return name != "" && parse.QuoteVariableName(name) == name &&
+		!strings.ContainsAny(name, ":~")
+}
+
+type documentFormattingParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+	// The formatting options sent by the client are ignored, since there is
+	// only one canonical style.
+}
+
+func (s *server) formatting(_ context.Context, params documentFormattingParams) (any, error) {
+	uri := params.TextDocument.URI
+	document, ok := s.documents[uri]
+	if !ok {
+		return nil, unknownDocument(uri)
+	}
+	formatted, err := elvfmt.Format(parse.Source{Name: string(uri), Code: document.code})
+	if err != nil || formatted == document.code {
+		// Code with parse errors can't be formatted; the errors are already
+		// published as diagnostics.
+		return []lsp.TextEdit{}, nil
+	}
+	return []lsp.TextEdit{{
+		Range:   lspRangeFromRange(document.code, diag.Ranging{From: 0, To: len(document.code)}),
+		NewText: formatted,
+	}}, nil
+}
+
+type documentSymbolParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+}
+
+func (s *server) documentSymbol(_ context.Context, params documentSymbolParams) (any, error) {
+	uri := params.TextDocument.URI
+	document, ok := s.documents[uri]
+	if !ok {
+		return nil, unknownDocument(uri)
+	}
+	return documentSymbols(document.code, collectSymbols(document.parseTree.Root)), nil
+}
+
+type workspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+func (s *server) workspaceSymbol(_ context.Context, params workspaceSymbolParams) (any, error) {
+	return s.workspaceSymbols(params.Query), nil
+}
+
 func (s *server) updateDocument(conn *jsonrpc2.Conn, uri lsp.DocumentURI, code string) {
 	tree, err := parse.Parse(parse.Source{Name: string(uri), Code: code}, parse.Config{})
 	s.documents[uri] = document{code, tree, err}
+	s.indexDocument(uri, s.documents[uri])
 	go func() {
-		// Convert the parse error to lsp.Diagnostic objects and publish them.
-		entries := parse.UnpackErrors(err)
-		diags := make([]lsp.Diagnostic, len(entries))
-		for i, err := range entries {
-			diags[i] = lsp.Diagnostic{
-				Range:    lspRangeFromRange(code, err),
-				Severity: lsp.DSError,
-				Source:   "parse",
-				Message:  err.Message,
-			}
-		}
+		// Compile the code without executing it, like -compileonly does, and
+		// convert the parse and compilation errors to lsp.Diagnostic objects.
+		_, compileErr := s.evaler.CheckTree(tree, nil)
+		parseErrs := parse.UnpackErrors(err)
+		// Compiling a tree with parse errors can produce compilation errors
+		// that are only artifacts of the parse errors; drop them.
+		compileErrs := slices.DeleteFunc(eval.UnpackCompilationErrors(compileErr),
+			func(compileErr *eval.CompilationError) bool {
+				return slices.ContainsFunc(parseErrs, func(parseErr *parse.Error) bool {
+					return touches(compileErr.Range(), parseErr.Range())
+				})
+			})
+		diags := make([]lsp.Diagnostic, 0)
+		diags = appendDiagnostics(diags, code, "parse", parseErrs)
+		diags = appendDiagnostics(diags, code, "compile", compileErrs)
 		conn.Notify(context.Background(), "textDocument/publishDiagnostics",
 			lsp.PublishDiagnosticsParams{URI: uri, Diagnostics: diags})
 	}()
 }
 
+// Reports whether two ranges overlap or are adjacent.
+func touches(r1, r2 diag.Ranging) bool {
+	return r1.From <= r2.To && r2.From <= r1.To
+}
+
+func appendDiagnostics[T diag.ErrorTag](diags []lsp.Diagnostic, code, source string, errs []*diag.Error[T]) []lsp.Diagnostic {
+	for _, err := range errs {
+		diags = append(diags, lsp.Diagnostic{
+			Range:    lspRangeFromRange(code, err),
+			Severity: lsp.DSError,
+			Source:   source,
+			Message:  err.Message,
+		})
+	}
+	return diags
+}
+
 func unknownDocument(uri lsp.DocumentURI) error {
 	return &jsonrpc2.Error{
 		Code:    jsonrpc2.CodeInvalidParams,
@@ -362,11 +698,18 @@ func lspRangeFromRange(s string, r diag.Ranger) lsp.Range {
 	}
 }
 
+// Converts an LSP position to a byte index in s. A character past the end of a
+// line is clamped to the line break, as required by the LSP spec.
 func lspPositionToIdx(s string, pos lsp.Position) int {
 	var idx int
 	walkString(s, func(i int, p lsp.Position) bool {
+		if p.Line > pos.Line {
+			// The character is past the end of the line; idx is the index of
+			// the line break.
+			return false
+		}
 		idx = i
-		return p.Line < pos.Line || (p.Line == pos.Line && p.Character < pos.Character)
+		return p.Line < pos.Line || p.Character < pos.Character
 	})
 	return idx
 }
@@ -380,26 +723,27 @@ func lspPositionFromIdx(s string, idx int) lsp.Position {
 	return pos
 }
 
-// Generates (index, lspPosition) pairs in s, stopping if f returns false.
+// Generates (index, lspPosition) pairs in s, stopping if f returns false. A
+// \r\n sequence is a single line break, and only the index of its \r is
+// generated.
 func walkString(s string, f func(i int, p lsp.Position) bool) {
 	var p lsp.Position
 	lastCR := false
 
 	for i, r := range s {
+		if r == '\n' && lastCR {
+			// The \n is part of a \r\n sequence, whose line break has been
+			// counted at the \r.
+			lastCR = false
+			continue
+		}
 		if !f(i, p) {
 			return
 		}
 		switch {
-		case r == '\r':
+		case r == '\r' || r == '\n':
 			p.Line++
 			p.Character = 0
-		case r == '\n':
-			if lastCR {
-				// Ignore \n if it's part of a \r\n sequence
-			} else {
-				p.Line++
-				p.Character = 0
-			}
 		case r <= 0xFFFF:
 			// Encoded in UTF-16 with one unit
 			p.Character++
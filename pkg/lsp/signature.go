@@ -0,0 +1,187 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+
+	lsp "pkg.nimblebun.works/go-lsp"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/mods/doc"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/parse/cmpd"
+	"src.elv.sh/pkg/parse/np"
+)
+
+type signatureHelp struct {
+	Signatures      []signatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature"`
+	ActiveParameter int                    `json:"activeParameter"`
+}
+
+type signatureInformation struct {
+	Label         string                 `json:"label"`
+	Documentation *lsp.MarkupContent     `json:"documentation,omitempty"`
+	Parameters    []parameterInformation `json:"parameters"`
+}
+
+type parameterInformation struct {
+	// A substring of the label of the signature.
+	Label string `json:"label"`
+}
+
+func (s *server) signatureHelp(_ context.Context, params lsp.TextDocumentPositionParams) (any, error) {
+	uri := params.TextDocument.URI
+	document, ok := s.documents[uri]
+	if !ok {
+		return nil, unknownDocument(uri)
+	}
+	pos := lspPositionToIdx(document.code, params.Position)
+	form := enclosingForm(document.parseTree.Root, pos)
+	if form == nil {
+		return nil, nil
+	}
+	name, ok := cmpd.StringLiteral(form.Head)
+	if !ok {
+		return nil, nil
+	}
+
+	var lambda *parse.Primary
+	var documentation *lsp.MarkupContent
+	if def := s.resolve(uri, document, newReference(name+eval.FnSuffix, form.Head)); def != nil {
+		lambda = fnLambda(def.node)
+	} else if sig, err := doc.Signature(name); err == nil {
+		// Builtin functions don't have Elvish source; parse the signature
+		// extracted from their elvdoc instead.
+		tree, err := parse.Parse(parse.Source{Name: "[signature]", Code: "{|" + sig + "|}"}, parse.Config{})
+		if err == nil {
+			lambda = fnLambdaFromCompound(tree.Root.Pipelines[0].Forms[0].Head)
+		}
+		if markdown, err := doc.Source(name); err == nil {
+			documentation = &lsp.MarkupContent{Kind: lsp.MKMarkdown, Value: markdown}
+		}
+	}
+	if lambda == nil {
+		return nil, nil
+	}
+
+	info, paramNodes := signatureFromLambda(name, lambda)
+	info.Documentation = documentation
+	return signatureHelp{
+		Signatures:      []signatureInformation{info},
+		ActiveParameter: activeParameter(form, pos, paramNodes),
+	}, nil
+}
+
+// enclosingForm finds the innermost form whose arguments contain pos, not
+// crossing any chunk boundary.
+func enclosingForm(root parse.Node, pos int) *parse.Form {
+	for _, n := range np.FindLeft(root, pos) {
+		switch n := n.(type) {
+		case *parse.Form:
+			if n.Head != nil && pos > n.Head.Range().To {
+				return n
+			}
+			return nil
+		case *parse.Primary:
+			// The chunk of a lambda or capture that only contains whitespace
+			// doesn't show up in the path, so check the primary itself.
+			switch n.Type {
+			case parse.Lambda, parse.OutputCapture, parse.ExceptionCapture:
+				if r := n.Range(); r.From < pos && pos < r.To {
+					return nil
+				}
+			}
+		case *parse.Chunk:
+			return nil
+		}
+	}
+	return nil
+}
+
+// fnLambda returns the body of the function whose name is defined by def, or
+// nil if def is not the name in a fn form.
+func fnLambda(def *parse.Compound) *parse.Primary {
+	form, ok := parse.Parent(def).(*parse.Form)
+	if !ok || len(form.Args) < 2 || form.Args[0] != def {
+		return nil
+	}
+	if head, _ := cmpd.StringLiteral(form.Head); head != "fn" {
+		return nil
+	}
+	return fnLambdaFromCompound(form.Args[1])
+}
+
+func fnLambdaFromCompound(n *parse.Compound) *parse.Primary {
+	if len(n.Indexings) != 1 || len(n.Indexings[0].Indices) > 0 {
+		return nil
+	}
+	if primary := n.Indexings[0].Head; primary.Type == parse.Lambda {
+		return primary
+	}
+	return nil
+}
+
+// signatureFromLambda builds the signature of a function named name from its
+// body. It also returns the nodes of the parameters, which are either
+// *parse.Compound for arguments or *parse.MapPair for options, in the order
+// they appear in the signature.
+func signatureFromLambda(name string, lambda *parse.Primary) (signatureInformation, []parse.Node) {
+	var paramNodes []parse.Node
+	for _, ch := range parse.Children(lambda) {
+		switch ch.(type) {
+		case *parse.Compound, *parse.MapPair:
+			if ch.Range().From < lambda.Chunk.Range().From {
+				paramNodes = append(paramNodes, ch)
+			}
+		}
+	}
+	info := signatureInformation{Parameters: make([]parameterInformation, len(paramNodes))}
+	labels := []string{parse.QuoteCommandName(name)}
+	for i, n := range paramNodes {
+		info.Parameters[i].Label = parse.SourceText(n)
+		labels = append(labels, info.Parameters[i].Label)
+	}
+	info.Label = strings.Join(labels, " ")
+	return info, paramNodes
+}
+
+// activeParameter finds the index of the parameter corresponding to the
+// argument or option at pos in form. It returns len(params) if there is no
+// such parameter, which clients treat as no active parameter.
+func activeParameter(form *parse.Form, pos int, params []parse.Node) int {
+	for _, opt := range form.Opts {
+		if r := opt.Range(); r.From <= pos && pos <= r.To {
+			key, _ := cmpd.StringLiteral(opt.Key)
+			for i, param := range params {
+				if param, ok := param.(*parse.MapPair); ok {
+					if paramKey, _ := cmpd.StringLiteral(param.Key); paramKey == key {
+						return i
+					}
+				}
+			}
+			return len(params)
+		}
+	}
+
+	// The index of the argument at pos.
+	argIndex := 0
+	for _, arg := range form.Args {
+		if pos <= arg.Range().To {
+			break
+		}
+		argIndex++
+	}
+	// Match the argument against positional parameters. A rest parameter
+	// consumes all the arguments beyond the preceding parameters.
+	for i, param := range params {
+		param, ok := param.(*parse.Compound)
+		if !ok {
+			continue
+		}
+		if text, _ := cmpd.StringLiteral(param); strings.HasPrefix(text, "@") || argIndex == 0 {
+			return i
+		}
+		argIndex--
+	}
+	return len(params)
+}
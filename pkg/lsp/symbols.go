@@ -0,0 +1,217 @@
+package lsp
+
+import (
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	lsp "pkg.nimblebun.works/go-lsp"
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/parse"
+)
+
+// symbolKind values from the LSP specification.
+type symbolKind int
+
+const (
+	symbolKindNamespace symbolKind = 3
+	symbolKindFunction  symbolKind = 12
+	symbolKindVariable  symbolKind = 13
+)
+
+type documentSymbol struct {
+	Name           string           `json:"name"`
+	Kind           symbolKind       `json:"kind"`
+	Range          lsp.Range        `json:"range"`
+	SelectionRange lsp.Range        `json:"selectionRange"`
+	Children       []documentSymbol `json:"children,omitempty"`
+}
+
+type symbolInformation struct {
+	Name          string       `json:"name"`
+	Kind          symbolKind   `json:"kind"`
+	Location      lsp.Location `json:"location"`
+	ContainerName string       `json:"containerName,omitempty"`
+}
+
+// symbol is a definition of a function, variable or namespace.
+type symbol struct {
+	name      string
+	kind      symbolKind
+	nameRange diag.Ranging
+	// Range of the entire form defining the symbol.
+	formRange diag.Ranging
+	// Symbols defined in the body of a function.
+	children []symbol
+}
+
+// collectSymbols finds all the symbols defined with var, fn and use in the tree
+// rooted at n. Symbols defined in the body of a function are nested under the
+// symbol of the function.
+func collectSymbols(n parse.Node) []symbol {
+	var symbols []symbol
+	for _, ch := range parse.Children(n) {
+		form, ok := ch.(*parse.Form)
+		if !ok {
+			symbols = append(symbols, collectSymbols(ch)...)
+			continue
+		}
+		nested := collectSymbols(form)
+		isFn := false
+		eachDefinedVariableInForm(form, func(name string, def *parse.Compound) {
+			sym := symbol{nameRange: newReference(name, def).nameRange, formRange: form.Range()}
+			switch {
+			case strings.HasSuffix(name, eval.FnSuffix):
+				sym.name, sym.kind = strings.TrimSuffix(name, eval.FnSuffix), symbolKindFunction
+				sym.children, isFn = nested, true
+			case strings.HasSuffix(name, eval.NsSuffix):
+				sym.name, sym.kind = strings.TrimSuffix(name, eval.NsSuffix), symbolKindNamespace
+			default:
+				sym.name, sym.kind = name, symbolKindVariable
+			}
+			symbols = append(symbols, sym)
+		})
+		if !isFn {
+			symbols = append(symbols, nested...)
+		}
+	}
+	return symbols
+}
+
+func documentSymbols(code string, symbols []symbol) []documentSymbol {
+	docSymbols := make([]documentSymbol, len(symbols))
+	for i, sym := range symbols {
+		docSymbols[i] = documentSymbol{
+			Name:           sym.name,
+			Kind:           sym.kind,
+			Range:          lspRangeFromRange(code, sym.formRange),
+			SelectionRange: lspRangeFromRange(code, sym.nameRange),
+			Children:       documentSymbols(code, sym.children),
+		}
+	}
+	return docSymbols
+}
+
+// flatSymbols converts symbols and their children to a flat list, in the order
+// they appear in the source.
+func flatSymbols(uri lsp.DocumentURI, code string, symbols []symbol, container string) []symbolInformation {
+	var infos []symbolInformation
+	for _, sym := range symbols {
+		infos = append(infos, symbolInformation{
+			Name: sym.name,
+			Kind: sym.kind,
+			Location: lsp.Location{
+				URI: uri, Range: lspRangeFromRange(code, sym.nameRange)},
+			ContainerName: container,
+		})
+		infos = append(infos, flatSymbols(uri, code, sym.children, sym.name)...)
+	}
+	return infos
+}
+
+// indexDocument updates the symbol index for the document at uri.
+func (s *server) indexDocument(uri lsp.DocumentURI, doc document) {
+	symbols := flatSymbols(uri, doc.code, collectSymbols(doc.parseTree.Root), "")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.symbols[uri] = symbols
+}
+
+// indexFile updates the symbol index for a document that is not open, reading
+// it from disk. The document is removed from the index if it can't be read.
+func (s *server) indexFile(uri lsp.DocumentURI) {
+	if doc, ok := s.readFile(uri); ok {
+		s.indexDocument(uri, doc)
+	} else {
+		s.unindex(uri)
+	}
+}
+
+// unindex removes the document at uri from the symbol index.
+func (s *server) unindex(uri lsp.DocumentURI) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.symbols, uri)
+	delete(s.files, uri)
+}
+
+// Limits of indexing the workspace, so that opening a large directory like the
+// home directory doesn't take too much time and memory.
+const (
+	maxIndexedFiles = 5000
+	maxIndexDepth   = 10
+)
+
+// Directories that are not indexed, in addition to hidden ones.
+var skippedDirs = map[string]bool{"vendor": true, "node_modules": true}
+
+// indexWorkspace indexes the .elv files under root, skipping hidden and vendor
+// directories. It is run in its own goroutine, and doesn't replace the symbols
+// of documents indexed in the meantime.
+func (s *server) indexWorkspace(root string) {
+	n := 0
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return nil
+		}
+		if d.IsDir() {
+			rel, _ := filepath.Rel(root, path)
+			if strings.HasPrefix(d.Name(), ".") || skippedDirs[d.Name()] ||
+				strings.Count(rel, string(filepath.Separator)) >= maxIndexDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".elv") {
+			return nil
+		}
+		if n == maxIndexedFiles {
+			return filepath.SkipAll
+		}
+		n++
+		uri := pathToURI(path)
+		doc, ok := s.readFile(uri)
+		if !ok {
+			return nil
+		}
+		symbols := flatSymbols(uri, doc.code, collectSymbols(doc.parseTree.Root), "")
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if _, indexed := s.symbols[uri]; !indexed {
+			s.symbols[uri] = symbols
+		}
+		return nil
+	})
+}
+
+// workspaceSymbols returns the indexed symbols whose names contain query,
+// ignoring case.
+func (s *server) workspaceSymbols(query string) []symbolInformation {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	query = strings.ToLower(query)
+	infos := []symbolInformation{}
+	for _, uri := range slices.Sorted(maps.Keys(s.symbols)) {
+		for _, info := range s.symbols[uri] {
+			if strings.Contains(strings.ToLower(info.Name), query) {
+				infos = append(infos, info)
+			}
+		}
+	}
+	return infos
+}
+
+// rootPath finds the path of the workspace root from the parameters of the
+// initialize request.
+func rootPath(params initializeParams) (string, bool) {
+	if params.RootURI != "" {
+		return uriToPath(params.RootURI)
+	}
+	if params.RootPath != "" {
+		return params.RootPath, true
+	}
+	return "", false
+}
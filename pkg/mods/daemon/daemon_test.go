@@ -103,6 +103,22 @@ func (c *mockClient) PrevCmd(upto int, prefix string) (storedefs.Cmd, error) {
 	return storedefs.Cmd{Text: prefix + "test", Seq: upto - 1}, nil
 }
 
+func (c *mockClient) SetCmdMeta(seq int, meta storedefs.CmdMeta) error {
+	return nil
+}
+
+func (c *mockClient) CmdMeta(seq int) (storedefs.CmdMeta, error) {
+	return storedefs.CmdMeta{}, nil
+}
+
+func (c *mockClient) SearchCmds(query string, opts storedefs.SearchOpts) ([]storedefs.Cmd, error) {
+	return nil, nil
+}
+
+func (c *mockClient) MergeCmds(cmds []storedefs.Cmd) (int, error) {
+	return len(cmds), nil
+}
+
 func (c *mockClient) AddDir(dir string, incFactor float64) error {
 	return nil
 }
@@ -116,3 +132,7 @@ func (c *mockClient) Dirs(blacklist map[string]struct{}) ([]storedefs.Dir, error
 		{Path: "/home/test", Score: 10.0},
 	}, nil
 }
+
+func (c *mockClient) MergeDirs(dirs []storedefs.Dir) (int, error) {
+	return len(dirs), nil
+}
@@ -86,6 +86,27 @@ func find(fm *eval.Frame, qs ...string) {
 
 // Source returns the doc source for a symbol.
 func Source(qname string) (string, error) {
+	entry, err := findEntry(qname)
+	if err != nil {
+		return "", err
+	}
+	return entry.FullContent(), nil
+}
+
+// Signature returns the signature of a function without surrounding pipes, like
+// "a @b". The function name is not prefixed with "$".
+func Signature(qname string) (string, error) {
+	entry, err := findEntry(qname)
+	if err != nil {
+		return "", err
+	}
+	if entry.Fn == nil {
+		return "", fmt.Errorf("no signature for %s", parse.Quote(qname))
+	}
+	return entry.Fn.Signature, nil
+}
+
+func findEntry(qname string) (elvdoc.Entry, error) {
 	isVar := strings.HasPrefix(qname, "$")
 	var ns string
 	if strings.ContainsRune(qname, ':') {
@@ -110,7 +131,7 @@ func Source(qname string) (string, error) {
 
 	docs, ok := docsMap()[ns]
 	if !ok {
-		return "", fmt.Errorf("no doc for %s", parse.Quote(qname))
+		return elvdoc.Entry{}, fmt.Errorf("no doc for %s", parse.Quote(qname))
 	}
 	var entries []elvdoc.Entry
 	if isVar {
@@ -120,11 +141,11 @@ func Source(qname string) (string, error) {
 	}
 	for _, entry := range entries {
 		if entry.Name == qname {
-			return entry.FullContent(), nil
+			return entry, nil
 		}
 	}
 
-	return "", fmt.Errorf("no doc for %s", parse.Quote(qname))
+	return elvdoc.Entry{}, fmt.Errorf("no doc for %s", parse.Quote(qname))
 }
 
 func symbols(fm *eval.Frame) error {
@@ -0,0 +1,49 @@
+// Package json implements the json: module, for querying data decoded from JSON.
+package json
+
+import (
+	"fmt"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/parse"
+)
+
+// Ns is the namespace for the json: module.
+var Ns = eval.BuildNsNamed("json").
+	AddGoFns(map[string]any{
+		"get":   get,
+		"query": query,
+	}).Ns()
+
+func get(v any, pathString string) (any, error) {
+	p, err := parsePath(pathString)
+	if err != nil {
+		return nil, err
+	}
+	var results []any
+	p.eval(v, func(result any) { results = append(results, result) })
+	switch len(results) {
+	case 0:
+		return nil, fmt.Errorf("no value at path %s", parse.Quote(pathString))
+	case 1:
+		return results[0], nil
+	default:
+		return nil, fmt.Errorf("multiple values at path %s; use json:query instead",
+			parse.Quote(pathString))
+	}
+}
+
+func query(fm *eval.Frame, v any, pathString string) error {
+	p, err := parsePath(pathString)
+	if err != nil {
+		return err
+	}
+	out := fm.ValueOutput()
+	var errOut error
+	p.eval(v, func(result any) {
+		if errOut == nil {
+			errOut = out.Put(result)
+		}
+	})
+	return errOut
+}
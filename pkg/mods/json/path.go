@@ -0,0 +1,201 @@
+package json
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
+)
+
+// A parsed path expression.
+type path []segment
+
+// A segment of a path expression, which selects zero or more values from each
+// value selected by the previous segment.
+type segment struct {
+	// Whether the segment applies to all descendants of the value (including
+	// itself) instead of just the value.
+	recursive bool
+	// Whether the segment selects all the elements of lists and all the values
+	// of maps.
+	wildcard bool
+	// Otherwise, the map key or list index to select: a string for a member
+	// segment like .foo, or an int for a bracket segment like [0].
+	key any
+}
+
+// PathError is thrown when a path expression can't be parsed.
+type PathError struct {
+	Path    string
+	Message string
+}
+
+func (e PathError) Error() string {
+	return fmt.Sprintf("bad path %s: %s", parse.Quote(e.Path), e.Message)
+}
+
+// Parses a path expression. The syntax is a subset of JSONPath and jq's path
+// syntax; see the documentation of json:query for details.
+func parsePath(s string) (path, error) {
+	rest := strings.TrimPrefix(s, "$")
+	if rest == "." {
+		return nil, nil
+	}
+	if rest != "" && rest[0] != '.' && rest[0] != '[' {
+		rest = "." + rest
+	}
+	bad := func(format string, args ...any) (path, error) {
+		return nil, PathError{s, fmt.Sprintf(format, args...)}
+	}
+	var p path
+	for rest != "" {
+		var seg segment
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			seg.recursive = true
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				break
+			}
+			fallthrough
+		case rest[0] == '.':
+			rest = strings.TrimPrefix(rest, ".")
+			if strings.HasPrefix(rest, "*") {
+				seg.wildcard = true
+				rest = rest[1:]
+				break
+			}
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return bad("empty member name")
+			}
+			seg.key, rest = rest[:end], rest[end:]
+		case rest[0] == '[':
+		default:
+			return bad("unexpected %q", rest[0])
+		}
+		if seg.key == nil && !seg.wildcard {
+			// A bracket segment.
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return bad("unclosed [")
+			}
+			inner := rest[1:end]
+			switch {
+			case inner == "*":
+				seg.wildcard = true
+			case strings.HasPrefix(inner, `"`):
+				// Find the end of the quoted string, which may contain ].
+				quoted, err := strconv.QuotedPrefix(rest[1:])
+				if err != nil {
+					return bad("invalid quoted key")
+				}
+				end = 1 + len(quoted)
+				if !strings.HasPrefix(rest[end:], "]") {
+					return bad("unclosed [")
+				}
+				seg.key, _ = strconv.Unquote(quoted)
+			case strings.HasPrefix(inner, "'"):
+				closing := strings.IndexByte(rest[2:], '\'')
+				if closing == -1 {
+					return bad("invalid quoted key")
+				}
+				end = 2 + closing + 1
+				if !strings.HasPrefix(rest[end:], "]") {
+					return bad("unclosed [")
+				}
+				seg.key = rest[2 : 2+closing]
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil {
+					return bad("invalid index %q", inner)
+				}
+				seg.key = i
+			}
+			rest = rest[end+1:]
+		}
+		p = append(p, seg)
+	}
+	return p, nil
+}
+
+// Calls f with each value selected by the path.
+func (p path) eval(v any, f func(any)) {
+	if len(p) == 0 {
+		f(v)
+		return
+	}
+	p[0].eval(v, func(selected any) { p[1:].eval(selected, f) })
+}
+
+func (seg segment) eval(v any, f func(any)) {
+	if seg.recursive {
+		walk(v, func(descendant any) { seg.evalOne(descendant, f) })
+	} else {
+		seg.evalOne(v, f)
+	}
+}
+
+func (seg segment) evalOne(v any, f func(any)) {
+	if seg.wildcard {
+		children(v, f)
+		return
+	}
+	switch vals.Kind(v) {
+	case "map":
+		if k, ok := seg.key.(string); ok && vals.HasKey(v, k) {
+			child, _ := vals.Index(v, k)
+			f(child)
+		}
+	case "list":
+		i, ok := seg.key.(int)
+		if !ok {
+			// Allow member segments like .0 to index lists.
+			var err error
+			i, err = strconv.Atoi(seg.key.(string))
+			if err != nil {
+				return
+			}
+		}
+		if child, err := vals.Index(v, i); err == nil {
+			f(child)
+		}
+	}
+}
+
+// Calls f with v and all its descendants, in pre-order.
+func walk(v any, f func(any)) {
+	f(v)
+	children(v, func(child any) { walk(child, f) })
+}
+
+// Calls f with each element of a list, or each value of a map in the order of
+// the keys.
+func children(v any, f func(any)) {
+	switch vals.Kind(v) {
+	case "list":
+		vals.Iterate(v, func(elem any) bool {
+			f(elem)
+			return true
+		})
+	case "map":
+		var keys []any
+		vals.IterateKeys(v, func(k any) bool {
+			keys = append(keys, k)
+			return true
+		})
+		sort.Slice(keys, func(i, j int) bool {
+			return vals.CmpTotal(keys[i], keys[j]) == vals.CmpLess
+		})
+		for _, k := range keys {
+			child, _ := vals.Index(v, k)
+			f(child)
+		}
+	}
+}
@@ -5,6 +5,7 @@ package math
 import (
 	"math"
 	"math/big"
+	"strconv"
 
 	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/eval/errs"
@@ -84,8 +85,9 @@ func abs(n vals.Num) vals.Num {
 }
 
 var (
-	big1 = big.NewInt(1)
-	big2 = big.NewInt(2)
+	big1  = big.NewInt(1)
+	big2  = big.NewInt(2)
+	big10 = big.NewInt(10)
 )
 
 func ceil(n vals.Num) vals.Num {
@@ -255,9 +257,12 @@ func isExactInt(n vals.Num) bool {
 	}
 }
 
-func round(n vals.Num) vals.Num {
-	return integerize(n,
-		math.Round,
+type roundOpts struct{ Digits int }
+
+func (*roundOpts) SetDefaultOptions() {}
+
+func round(opts roundOpts, n vals.Num) (vals.Num, error) {
+	return roundToDigits(n, opts.Digits, math.Round,
 		func(n *big.Rat) *big.Int {
 			q, m := new(big.Int).QuoRem(n.Num(), n.Denom(), new(big.Int))
 			m = m.Mul(m, big2)
@@ -271,9 +276,8 @@ func round(n vals.Num) vals.Num {
 		})
 }
 
-func roundToEven(n vals.Num) vals.Num {
-	return integerize(n,
-		math.RoundToEven,
+func roundToEven(opts roundOpts, n vals.Num) (vals.Num, error) {
+	return roundToDigits(n, opts.Digits, math.RoundToEven,
 		func(n *big.Rat) *big.Int {
 			q, m := new(big.Int).QuoRem(n.Num(), n.Denom(), new(big.Int))
 			m = m.Mul(m, big2)
@@ -287,6 +291,58 @@ func roundToEven(n vals.Num) vals.Num {
 		})
 }
 
+// The maximum absolute value of &digits when rounding exact numbers, since
+// the results can get arbitrarily large.
+const maxExactDigits = 100000
+
+// Rounds n to a multiple of 10^-digits, using fnFloat and fnRat to round to
+// integers. The result is exact if n is exact.
+func roundToDigits(n vals.Num, digits int, fnFloat func(float64) float64, fnRat func(*big.Rat) *big.Int) (vals.Num, error) {
+	if digits == 0 {
+		return integerize(n, fnFloat, fnRat), nil
+	}
+	if f, ok := n.(float64); ok {
+		return roundFloatToDigits(f, digits, fnFloat), nil
+	}
+	if digits > 0 && isExactInt(n) {
+		return n, nil
+	}
+	if digits < -maxExactDigits || digits > maxExactDigits {
+		return nil, errs.OutOfRange{What: "digits",
+			ValidLow: strconv.Itoa(-maxExactDigits), ValidHigh: strconv.Itoa(maxExactDigits),
+			Actual: strconv.Itoa(digits)}
+	}
+	exp := big.NewInt(int64(digits))
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big10, exp.Abs(exp), nil))
+	if digits < 0 {
+		scale.Inv(scale)
+	}
+	scaled := new(big.Rat).Mul(vals.PromoteToBigRat(n), scale)
+	rounded := integerize(vals.NormalizeBigRat(scaled), fnFloat, fnRat)
+	return vals.NormalizeBigRat(new(big.Rat).Quo(vals.PromoteToBigRat(rounded), scale)), nil
+}
+
+// Rounds f to a multiple of 10^-digits. Since float64 has about 17 significant
+// digits and a maximum exponent of about 308, f is returned unchanged when it
+// has no digits beyond the ones to round to, and rounding to a multiple of a
+// power of 10 larger than any float64 results in 0.
+func roundFloatToDigits(f float64, digits int, fnFloat func(float64) float64) float64 {
+	if math.IsInf(f, 0) || math.IsNaN(f) || f == 0 {
+		return f
+	}
+	if digits > 0 {
+		// The scaled value can only be an integer when it is at least 2^53,
+		// including when it overflows to +Inf.
+		if digits > 308 || math.Abs(f)*math.Pow10(digits) >= 1<<53 {
+			return f
+		}
+	} else if digits < -308 {
+		return math.Copysign(0, f)
+	}
+	s := math.Pow10(digits)
+	return fnFloat(f*s) / s
+}
+
 func trunc(n vals.Num) vals.Num {
 	return integerize(n,
 		math.Trunc,
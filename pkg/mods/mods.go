@@ -7,6 +7,7 @@ import (
 	"src.elv.sh/pkg/mods/epm"
 	"src.elv.sh/pkg/mods/file"
 	"src.elv.sh/pkg/mods/flag"
+	"src.elv.sh/pkg/mods/json"
 	"src.elv.sh/pkg/mods/math"
 	"src.elv.sh/pkg/mods/md"
 	"src.elv.sh/pkg/mods/os"
@@ -17,6 +18,7 @@ import (
 	"src.elv.sh/pkg/mods/runtime"
 	"src.elv.sh/pkg/mods/str"
 	"src.elv.sh/pkg/mods/unix"
+	vi_binding "src.elv.sh/pkg/mods/vi-binding"
 )
 
 // AddTo adds all standard library modules to the Evaler.
@@ -35,9 +37,11 @@ func AddTo(ev *eval.Evaler) {
 	ev.AddModule("doc", doc.Ns)
 	ev.AddModule("os", os.Ns)
 	ev.AddModule("md", md.Ns)
+	ev.AddModule("json", json.Ns)
 	if unix.ExposeUnixNs {
 		ev.AddModule("unix", unix.Ns)
 	}
 	ev.BundledModules["epm"] = epm.Code
 	ev.BundledModules["readline-binding"] = readline_binding.Code
+	ev.BundledModules["vi-binding"] = vi_binding.Code
 }
@@ -40,6 +40,9 @@ var Ns = eval.BuildNsNamed("os").
 
 		"eval-symlinks": filepath.EvalSymlinks,
 
+		// Processes.
+		"run": run,
+
 		// Temp file/dir.
 		"temp-dir":  TempDir,
 		"temp-file": TempFile,
@@ -0,0 +1,126 @@
+package os
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/eval/vals"
+)
+
+// How long to wait for the output of a command to be closed after it has been
+// killed because of a timeout. This can happen when the command has started
+// other processes that inherited its stdout or stderr.
+const runWaitDelay = time.Second
+
+type runOpts struct {
+	Env     vals.Map
+	Dir     string
+	Stdin   any
+	Timeout float64
+}
+
+func (*runOpts) SetDefaultOptions() {}
+
+func run(fm *eval.Frame, opts runOpts, name string, args ...any) (vals.Map, error) {
+	if opts.Timeout < 0 {
+		return nil, errs.OutOfRange{What: "timeout",
+			ValidLow: "0", ValidHigh: "+Inf", Actual: vals.ToString(opts.Timeout)}
+	}
+	stdin, err := runStdin(fm, opts.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	env, err := runEnv(opts.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := fm.Context()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx,
+			time.Duration(opts.Timeout*float64(time.Second)))
+		defer cancel()
+	}
+	strArgs := make([]string, len(args))
+	for i, arg := range args {
+		strArgs[i] = vals.ToString(arg)
+	}
+	cmd := exec.CommandContext(ctx, name, strArgs...)
+	cmd.Dir = opts.Dir
+	cmd.Env = env
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	cmd.WaitDelay = runWaitDelay
+
+	start := time.Now()
+	err = cmd.Run()
+	duration := time.Since(start)
+	if cmd.ProcessState == nil {
+		// The command could not be started.
+		return nil, err
+	}
+	if fm.Canceled() {
+		return nil, eval.ErrInterrupted
+	}
+
+	ws := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	var signal any
+	if ws.Signaled() {
+		signal = ws.Signal().String()
+	}
+	exit := eval.NewExternalCmdExit(name, ws, cmd.ProcessState.Pid())
+	return vals.MakeMap(
+		"stdout", stdout.String(),
+		"stderr", stderr.String(),
+		"exit-status", eval.ExitStatus(exit),
+		"signal", signal,
+		"duration", duration.Seconds(),
+		"timed-out", ctx.Err() == context.DeadlineExceeded), nil
+}
+
+func runStdin(fm *eval.Frame, v any) (io.Reader, error) {
+	switch v := v.(type) {
+	case nil:
+		return fm.InputFile(), nil
+	case string:
+		return strings.NewReader(v), nil
+	case *os.File:
+		return v, nil
+	default:
+		return nil, errs.BadValue{What: "option &stdin",
+			Valid: "string, file or $nil", Actual: vals.ReprPlain(v)}
+	}
+}
+
+// Returns the environment of the command: the current environment, with the
+// variables in m added or overridden. Returns nil if m is nil, which makes the
+// command use the current environment.
+func runEnv(m vals.Map) ([]string, error) {
+	if m == nil {
+		return nil, nil
+	}
+	env := os.Environ()
+	for it := m.Iterator(); it.HasElem(); it.Next() {
+		k, v := it.Elem()
+		name, ok1 := k.(string)
+		value, ok2 := v.(string)
+		if !ok1 || !ok2 {
+			return nil, errs.BadValue{What: "option &env",
+				Valid:  "map from strings to strings",
+				Actual: vals.ReprPlain(k) + " => " + vals.ReprPlain(v)}
+		}
+		// Later entries take precedence over earlier ones.
+		env = append(env, name+"="+value)
+	}
+	return env, nil
+}
@@ -0,0 +1,267 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"src.elv.sh/pkg/env"
+	"src.elv.sh/pkg/fsutil"
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+// A command read from the history file of another shell.
+type shellCmd struct {
+	text string
+	// Zero if unknown.
+	start time.Time
+	// Only meaningful if hasDuration is true.
+	duration    time.Duration
+	hasDuration bool
+}
+
+var shellHistoryParsers = map[string]func(io.Reader) ([]shellCmd, error){
+	"bash": parseBashHistory,
+	"zsh":  parseZshHistory,
+	"fish": parseFishHistory,
+}
+
+type importShellHistoryOpts struct{ File string }
+
+func (*importShellHistoryOpts) SetDefaultOptions() {}
+
+func importShellHistory(s storedefs.Store, opts importShellHistoryOpts, shell string) (int, error) {
+	parse, ok := shellHistoryParsers[shell]
+	if !ok {
+		return 0, fmt.Errorf("unsupported shell %q; must be one of bash, zsh and fish", shell)
+	}
+	name := opts.File
+	if name == "" {
+		var err error
+		name, err = defaultShellHistoryFile(shell)
+		if err != nil {
+			return 0, err
+		}
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	cmds, err := parse(f)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	sortShellCmds(cmds)
+	storeCmds := make([]storedefs.Cmd, len(cmds))
+	for i, cmd := range cmds {
+		storeCmds[i] = cmd.storeCmd()
+	}
+	return s.MergeCmds(storeCmds)
+}
+
+// Converts the command to a storedefs.Cmd, with metadata for the fields known
+// from the history file.
+func (cmd shellCmd) storeCmd() storedefs.Cmd {
+	if cmd.start.IsZero() {
+		return storedefs.Cmd{Text: cmd.text}
+	}
+	meta := storedefs.CmdMeta{Start: cmd.start, Duration: cmd.duration,
+		Unknown: storedefs.CmdMetaDir | storedefs.CmdMetaStatus}
+	if !cmd.hasDuration {
+		meta.Unknown |= storedefs.CmdMetaDuration
+	}
+	return storedefs.Cmd{Text: cmd.text, Meta: &meta}
+}
+
+func defaultShellHistoryFile(shell string) (string, error) {
+	if shell == "fish" {
+		if dataHome := os.Getenv(env.XDG_DATA_HOME); dataHome != "" {
+			return filepath.Join(dataHome, "fish", "fish_history"), nil
+		}
+	}
+	home, err := fsutil.GetHome("")
+	if err != nil {
+		return "", err
+	}
+	switch shell {
+	case "bash":
+		return filepath.Join(home, ".bash_history"), nil
+	case "zsh":
+		return filepath.Join(home, ".zsh_history"), nil
+	default:
+		return filepath.Join(home, ".local", "share", "fish", "fish_history"), nil
+	}
+}
+
+// Sorts commands in chronological order. Commands without a start time are
+// kept right after the command preceding them in the history file.
+func sortShellCmds(cmds []shellCmd) {
+	type keyed struct {
+		key time.Time
+		cmd shellCmd
+	}
+	keyedCmds := make([]keyed, len(cmds))
+	var last time.Time
+	for i, cmd := range cmds {
+		if !cmd.start.IsZero() {
+			last = cmd.start
+		}
+		keyedCmds[i] = keyed{last, cmd}
+	}
+	sort.SliceStable(keyedCmds, func(i, j int) bool {
+		return keyedCmds[i].key.Before(keyedCmds[j].key)
+	})
+	for i, k := range keyedCmds {
+		cmds[i] = k.cmd
+	}
+}
+
+var bashTimestamp = regexp.MustCompile(`^#(\d+)$`)
+
+// Parses bash history. If the file contains timestamps (written when
+// HISTTIMEFORMAT is set), the lines between two timestamps form one command,
+// which may span multiple lines; otherwise each line is a command.
+func parseBashHistory(r io.Reader) ([]shellCmd, error) {
+	var cmds []shellCmd
+	var lines []string
+	var start time.Time
+	timestamped := false
+	flush := func() {
+		if text := strings.TrimRight(strings.Join(lines, "\n"), "\n"); text != "" {
+			cmds = append(cmds, shellCmd{text: text, start: start})
+		}
+		lines = nil
+	}
+	err := eachLine(r, func(line string) {
+		if m := bashTimestamp.FindStringSubmatch(line); m != nil {
+			flush()
+			start = parseUnix(m[1])
+			timestamped = true
+		} else if timestamped {
+			lines = append(lines, line)
+		} else if line != "" {
+			cmds = append(cmds, shellCmd{text: line})
+		}
+	})
+	flush()
+	return cmds, err
+}
+
+var zshExtended = regexp.MustCompile(`^: *(\d+):(\d+);`)
+
+// Parses zsh history, either in the plain format or in the extended format
+// (": <start>:<duration>;<command>") written when EXTENDED_HISTORY is set.
+// Newlines in a command are preceded by a backslash.
+func parseZshHistory(r io.Reader) ([]shellCmd, error) {
+	var cmds []shellCmd
+	var cur *shellCmd
+	err := eachLine(r, func(line string) {
+		line = unmetafyZsh(line)
+		if cur == nil {
+			cmd := shellCmd{text: line}
+			if m := zshExtended.FindStringSubmatch(line); m != nil {
+				duration, _ := strconv.Atoi(m[2])
+				cmd = shellCmd{text: line[len(m[0]):], start: parseUnix(m[1]),
+					duration: time.Duration(duration) * time.Second, hasDuration: true}
+			}
+			cur = &cmd
+		} else {
+			cur.text += line
+		}
+		if strings.HasSuffix(cur.text, `\`) {
+			cur.text = cur.text[:len(cur.text)-1] + "\n"
+			return
+		}
+		if cur.text != "" {
+			cmds = append(cmds, *cur)
+		}
+		cur = nil
+	})
+	if cur != nil && cur.text != "" {
+		cmds = append(cmds, *cur)
+	}
+	return cmds, err
+}
+
+// Zsh writes bytes that are special to it in history files as 0x83 followed by
+// the byte XOR 32.
+func unmetafyZsh(s string) string {
+	if !strings.Contains(s, "\x83") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x83 && i+1 < len(s) {
+			i++
+			sb.WriteByte(s[i] ^ 32)
+		} else {
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+// Parses fish history, which is a subset of YAML. Each entry starts with a
+// "- cmd: <command>" line, followed by a "  when: <start>" line and optionally
+// other indented lines. In the command, newlines are written as "\n" and
+// backslashes as "\\".
+func parseFishHistory(r io.Reader) ([]shellCmd, error) {
+	var cmds []shellCmd
+	err := eachLine(r, func(line string) {
+		if text, ok := strings.CutPrefix(line, "- cmd: "); ok {
+			cmds = append(cmds, shellCmd{text: unescapeFish(text)})
+		} else if when, ok := strings.CutPrefix(line, "  when: "); ok && len(cmds) > 0 {
+			cmds[len(cmds)-1].start = parseUnix(when)
+		}
+	})
+	return cmds, err
+}
+
+func unescapeFish(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case 'n':
+				sb.WriteByte('\n')
+				i++
+				continue
+			case '\\':
+				sb.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// Calls f with each line in r, without the line ending.
+func eachLine(r io.Reader, f func(string)) error {
+	scanner := bufio.NewScanner(r)
+	// Commands can be long; allow lines of up to 16MiB.
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		f(string(bytes.TrimSuffix(scanner.Bytes(), []byte("\r"))))
+	}
+	return scanner.Err()
+}
+
+// Parses a Unix timestamp in seconds, returning the zero time if s is invalid.
+func parseUnix(s string) time.Time {
+	sec, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
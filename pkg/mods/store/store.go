@@ -2,7 +2,9 @@ package store
 
 import (
 	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/store/storedefs"
+	"src.elv.sh/pkg/store/storeio"
 )
 
 func Ns(s storedefs.Store) *eval.Ns {
@@ -12,12 +14,67 @@ func Ns(s storedefs.Store) *eval.Ns {
 			"add-cmd":      s.AddCmd,
 			"del-cmd":      s.DelCmd,
 			"cmd":          s.Cmd,
-			"cmds":         s.CmdsWithSeq,
-			"next-cmd":     s.NextCmd,
-			"prev-cmd":     s.PrevCmd,
+			"cmds": func(from, upto int) ([]vals.Map, error) {
+				cmds, err := s.CmdsWithSeq(from, upto)
+				return cmdsToMaps(cmds), err
+			},
+			"next-cmd": func(from int, prefix string) (vals.Map, error) {
+				cmd, err := s.NextCmd(from, prefix)
+				return cmdToMap(cmd), err
+			},
+			"prev-cmd": func(upto int, prefix string) (vals.Map, error) {
+				cmd, err := s.PrevCmd(upto, prefix)
+				return cmdToMap(cmd), err
+			},
+			"search-cmds": func(opts searchOpts, query string) ([]vals.Map, error) {
+				mode, err := storedefs.ParseSearchMode(opts.Mode)
+				if err != nil {
+					return nil, err
+				}
+				cmds, err := s.SearchCmds(query, storedefs.SearchOpts{
+					Mode: mode, Dedup: opts.Dedup, Limit: opts.Limit})
+				return cmdsToMaps(cmds), err
+			},
 
 			"add-dir": func(dir string) error { return s.AddDir(dir, 1) },
 			"del-dir": s.DelDir,
 			"dirs":    func() ([]storedefs.Dir, error) { return s.Dirs(storedefs.NoBlacklist) },
+
+			"export": func(fm *eval.Frame) error {
+				return storeio.Export(fm.ByteOutput(), s)
+			},
+			"import": func(fm *eval.Frame) (vals.Map, error) {
+				stats, err := storeio.Import(fm.InputFile(), s)
+				if err != nil {
+					return nil, err
+				}
+				return vals.MakeMap("cmds", stats.Cmds, "dirs", stats.Dirs), nil
+			},
+			"import-shell-history": func(opts importShellHistoryOpts, shell string) (int, error) {
+				return importShellHistory(s, opts, shell)
+			},
 		}).Ns()
 }
+
+type searchOpts struct {
+	Mode  string
+	Dedup bool
+	Limit int
+}
+
+func (opts *searchOpts) SetDefaultOptions() {
+	opts.Mode = "substring"
+	opts.Dedup = true
+}
+
+func cmdsToMaps(cmds []storedefs.Cmd) []vals.Map {
+	maps := make([]vals.Map, len(cmds))
+	for i, cmd := range cmds {
+		maps[i] = cmdToMap(cmd)
+	}
+	return maps
+}
+
+func cmdToMap(cmd storedefs.Cmd) vals.Map {
+	return cmd.Meta.AddToMap(vals.MakeMap("text", cmd.Text, "seq", cmd.Seq))
+}
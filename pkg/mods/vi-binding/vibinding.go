@@ -0,0 +1,8 @@
+package vi_binding
+
+import _ "embed"
+
+// Code contains the source code of the vi-binding module.
+//
+//go:embed vi-binding.elv
+var Code string
@@ -0,0 +1,85 @@
+package parseutil
+
+import (
+	"src.elv.sh/pkg/parse"
+)
+
+// Bracket represents a pair of matching brackets in code.
+type Bracket struct {
+	// Byte index of the opening bracket.
+	Open int
+	// Byte index just after the opening bracket. This is Open+2 for the "?("
+	// of exception captures, and Open+1 otherwise.
+	Body int
+	// Byte index of the closing bracket, or -1 if the bracket is not closed.
+	Close int
+}
+
+var closingBracketOf = map[string]string{
+	"{": "}", "[": "]", "(": ")", "?(": ")",
+}
+
+// Brackets parses the code and returns all the brackets in it, ordered by
+// their opening brackets. Since the parse tree is used, brackets in string
+// literals and comments are not included. Code with parse errors is
+// supported, as long as the parser was able to reach the brackets.
+func Brackets(code string) []Bracket {
+	tree, _ := parse.Parse(parse.Source{Name: "[unknown]", Code: code}, parse.Config{})
+	var brackets []Bracket
+	// Indices into brackets for brackets that are not closed yet.
+	var open []int
+	var walk func(n parse.Node)
+	walk = func(n parse.Node) {
+		if sep, ok := n.(*parse.Sep); ok {
+			text := parse.SourceText(sep)
+			r := sep.Range()
+			if _, ok := closingBracketOf[text]; ok {
+				open = append(open, len(brackets))
+				brackets = append(brackets, Bracket{r.From, r.To, -1})
+			} else if len(open) > 0 {
+				last := &brackets[open[len(open)-1]]
+				if text == closingBracketOf[code[last.Open:last.Body]] {
+					last.Close = r.From
+					open = open[:len(open)-1]
+				}
+			}
+			return
+		}
+		for _, ch := range parse.Children(n) {
+			walk(ch)
+		}
+	}
+	walk(tree.Root)
+	return brackets
+}
+
+// EnclosingBracket returns the innermost bracket that encloses pos, that is,
+// whose body starts at or before pos, and whose closing bracket is at or after
+// pos if it is closed.
+func EnclosingBracket(brackets []Bracket, pos int) (Bracket, bool) {
+	for i := len(brackets) - 1; i >= 0; i-- {
+		b := brackets[i]
+		if b.Body <= pos && (b.Close == -1 || pos <= b.Close) {
+			return b, true
+		}
+	}
+	return Bracket{}, false
+}
+
+// LineIndent returns the whitespace at the start of the line containing pos.
+func LineIndent(code string, pos int) string {
+	start := LineStart(code, pos)
+	end := start
+	for end < len(code) && (code[end] == ' ' || code[end] == '\t') {
+		end++
+	}
+	return code[start:end]
+}
+
+// LineStart returns the byte index of the start of the line containing pos.
+func LineStart(code string, pos int) int {
+	for pos > 0 && code[pos-1] != '\n' {
+		pos--
+	}
+	return pos
+}
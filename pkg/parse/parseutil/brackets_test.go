@@ -0,0 +1,46 @@
+package parseutil
+
+import (
+	"testing"
+
+	"src.elv.sh/pkg/tt"
+)
+
+var Args = tt.Args
+
+func TestBrackets(t *testing.T) {
+	tt.Test(t, Brackets,
+		Args("f { echo [a b] (x) ?(y) }").
+			Rets([]Bracket{{2, 3, 24}, {9, 10, 13}, {15, 16, 17}, {19, 21, 22}}),
+		tt.It("ignores brackets in strings and comments").
+			Args("f {\n  echo 'a{' # {\n").Rets([]Bracket{{2, 3, -1}}),
+		tt.It("supports indexing and braced lists").
+			Args("echo $a[0] {a,b}").Rets([]Bracket{{7, 8, 9}, {11, 12, 15}}),
+		tt.It("supports unclosed brackets").
+			Args("fn f {|x|\n  put (").Rets([]Bracket{{5, 6, -1}, {16, 17, -1}}),
+		Args("echo }").Rets([]Bracket(nil)),
+	)
+}
+
+func TestEnclosingBracket(t *testing.T) {
+	brackets := Brackets("f { [a] b }")
+	tt.Test(t, tt.Fn(func(pos int) (Bracket, bool) {
+		return EnclosingBracket(brackets, pos)
+	}).Named("EnclosingBracket"),
+		Args(2).Rets(Bracket{}, false),
+		Args(3).Rets(Bracket{2, 3, 10}, true),
+		Args(5).Rets(Bracket{4, 5, 6}, true),
+		Args(6).Rets(Bracket{4, 5, 6}, true),
+		Args(7).Rets(Bracket{2, 3, 10}, true),
+		Args(10).Rets(Bracket{2, 3, 10}, true),
+		Args(11).Rets(Bracket{}, false),
+	)
+}
+
+func TestLineIndent(t *testing.T) {
+	tt.Test(t, LineIndent,
+		Args("foo", 1).Rets(""),
+		Args("foo\n  \tbar", 8).Rets("  \t"),
+		Args("foo\n  bar", 4).Rets("  "),
+	)
+}
@@ -31,3 +31,50 @@ func isCompound(n parse.Node) bool {
 	_, ok := n.(*parse.Compound)
 	return ok
 }
+
+// InStringLiteral returns whether pos is inside a single-quoted or
+// double-quoted string literal in code, that is, after its opening quote and
+// before its closing quote. Positions at the end of an unterminated string
+// literal are also inside it.
+func InStringLiteral(code string, pos int) bool {
+	tree, _ := parse.Parse(parse.Source{Name: "[unknown]", Code: code}, parse.Config{})
+	return inStringLiteral(tree.Root, pos)
+}
+
+func inStringLiteral(n parse.Node, pos int) bool {
+	r := n.Range()
+	if pos <= r.From || r.To < pos {
+		return false
+	}
+	if pn, ok := n.(*parse.Primary); ok {
+		switch pn.Type {
+		case parse.SingleQuoted, parse.DoubleQuoted:
+			return pos < r.To || !isTerminated(parse.SourceText(pn))
+		}
+	}
+	for _, ch := range parse.Children(n) {
+		if inStringLiteral(ch, pos) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns whether the source text of a quoted string has its closing quote.
+func isTerminated(text string) bool {
+	if text[0] == '\'' {
+		// Single quotes inside the string are doubled, so the string is
+		// terminated iff there are an odd number of quotes after the opening
+		// one.
+		return strings.Count(text[1:], "'")%2 == 1
+	}
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,26 @@
 package parseutil
 
-import "testing"
+import (
+	"testing"
+
+	"src.elv.sh/pkg/tt"
+)
 
 func Test(t *testing.T) {
 	// Required to get accurate test coverage report.
 }
+
+func TestInStringLiteral(t *testing.T) {
+	tt.Test(t, InStringLiteral,
+		Args("echo 'a b'", 5).Rets(false),
+		Args("echo 'a b'", 6).Rets(true),
+		Args("echo 'a b'", 9).Rets(true),
+		Args("echo 'a b'", 10).Rets(false),
+		Args(`f { echo "a\"`, 13).Rets(true),
+		Args(`f { echo "a"`, 12).Rets(false),
+		Args("echo 'a''", 9).Rets(true),
+		Args("echo 'a'''", 10).Rets(false),
+		Args("f { echo a", 10).Rets(false),
+		Args("echo # 'a", 9).Rets(false),
+	)
+}
@@ -20,7 +20,9 @@ func rcPath() (string, error) {
 	}
 }
 
-func libPaths() ([]string, error) {
+// LibPaths returns the directories to search for modules, in order of
+// priority. This is the default value of $runtime:lib-dirs.
+func LibPaths() ([]string, error) {
 	var paths []string
 
 	if configHome := os.Getenv(env.XDG_CONFIG_HOME); configHome != "" {
@@ -68,7 +70,7 @@ func daemonPaths(p *prog.DaemonPaths) (*daemondefs.SpawnConfig, error) {
 	db := p.DB
 	if db == "" {
 		var err error
-		db, err = dbPath()
+		db, err = DBPath()
 		if err != nil {
 			return nil, err
 		}
@@ -80,7 +82,8 @@ func daemonPaths(p *prog.DaemonPaths) (*daemondefs.SpawnConfig, error) {
 	return &daemondefs.SpawnConfig{DbPath: db, SockPath: sock, RunDir: runDir}, nil
 }
 
-func dbPath() (string, error) {
+// DBPath returns the default path of the database file used by the daemon.
+func DBPath() (string, error) {
 	if stateHome := os.Getenv(env.XDG_STATE_HOME); stateHome != "" {
 		return filepath.Join(stateHome, "elvish", "db.bolt"), nil
 	} else if stateHome, err := defaultStateHome(); err == nil {
@@ -140,7 +140,7 @@ func (p *Program) makeEvaler(stderr io.Writer, interactive bool) *eval.Evaler {
 		}
 	}
 
-	libs, err := libPaths()
+	libs, err := LibPaths()
 	if err != nil {
 		fmt.Fprintln(stderr, "Warning: resolving lib paths:", err)
 	} else {
@@ -192,6 +192,8 @@ func evalInTTY(fds [3]*os.File, ev *eval.Evaler, ed editor, src parse.Source) er
 	ctx, done := eval.ListenInterrupts()
 	err := ev.Eval(src, eval.EvalCfg{
 		Ports: ports, Interrupts: ctx, PutInFg: true,
+		// Only enable job control for interactive sessions.
+		JobControl: ed != nil && sys.IsATTY(fds[0].Fd()),
 	})
 	done()
 	if ed != nil {
@@ -3,6 +3,7 @@ package store
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 
 	bolt "go.etcd.io/bbolt"
 	. "src.elv.sh/pkg/store/storedefs"
@@ -13,6 +14,10 @@ func init() {
 		_, err := tx.CreateBucketIfNotExists([]byte(bucketCmd))
 		return err
 	}
+	initDB["initialize command metadata table"] = func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketCmdMeta))
+		return err
+	}
 }
 
 // NextCmdSeq returns the next sequence number of the command history.
@@ -43,11 +48,15 @@ func (s *dbStore) AddCmd(cmd string) (int, error) {
 	return int(seq), err
 }
 
-// DelCmd deletes a command history item with the given sequence number.
+// DelCmd deletes a command history item with the given sequence number, along
+// with its metadata.
 func (s *dbStore) DelCmd(seq int) error {
 	return s.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(bucketCmd))
-		return b.Delete(marshalSeq(uint64(seq)))
+		err := tx.Bucket([]byte(bucketCmd)).Delete(marshalSeq(uint64(seq)))
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(bucketCmdMeta)).Delete(marshalSeq(uint64(seq)))
 	})
 }
 
@@ -66,14 +75,114 @@ func (s *dbStore) Cmd(seq int) (string, error) {
 	return cmd, err
 }
 
+// SetCmdMeta sets the metadata of the command with the specified sequence
+// number.
+func (s *dbStore) SetCmdMeta(seq int, meta CmdMeta) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		k := marshalSeq(uint64(seq))
+		if tx.Bucket([]byte(bucketCmd)).Get(k) == nil {
+			return ErrNoMatchingCmd
+		}
+		v, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(bucketCmdMeta)).Put(k, v)
+	})
+}
+
+// MergeCmds adds commands from another history to the end of the command
+// history, along with their metadata, and returns the number of commands
+// added. The Seq field of the commands is ignored.
+//
+// Commands that already exist in the history are skipped. A command with a
+// known start time already exists if there is a command with the same text and
+// start time; a command without a known start time already exists if there is
+// a command with the same text. This includes commands added earlier in the
+// same call.
+func (s *dbStore) MergeCmds(cmds []Cmd) (int, error) {
+	added := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketCmd))
+		metaBucket := tx.Bucket([]byte(bucketCmdMeta))
+
+		type textAndStart struct {
+			text  string
+			start int64
+		}
+		texts := make(map[string]bool)
+		textsAndStarts := make(map[textAndStart]bool)
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			texts[string(v)] = true
+			if meta := unmarshalMeta(metaBucket.Get(k)); meta != nil && meta.Has(CmdMetaStart) {
+				textsAndStarts[textAndStart{string(v), meta.Start.UnixNano()}] = true
+			}
+		}
+
+		for _, cmd := range cmds {
+			if cmd.Meta != nil && cmd.Meta.Has(CmdMetaStart) {
+				key := textAndStart{cmd.Text, cmd.Meta.Start.UnixNano()}
+				if textsAndStarts[key] {
+					continue
+				}
+				textsAndStarts[key] = true
+			} else if texts[cmd.Text] {
+				continue
+			}
+			texts[cmd.Text] = true
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			k := marshalSeq(seq)
+			err = b.Put(k, []byte(cmd.Text))
+			if err != nil {
+				return err
+			}
+			if cmd.Meta != nil {
+				v, err := json.Marshal(cmd.Meta)
+				if err != nil {
+					return err
+				}
+				err = metaBucket.Put(k, v)
+				if err != nil {
+					return err
+				}
+			}
+			added++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+// CmdMeta queries the metadata of the command with the specified sequence
+// number.
+func (s *dbStore) CmdMeta(seq int) (CmdMeta, error) {
+	var meta CmdMeta
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(bucketCmdMeta)).Get(marshalSeq(uint64(seq)))
+		if v == nil {
+			return ErrNoCmdMeta
+		}
+		return json.Unmarshal(v, &meta)
+	})
+	return meta, err
+}
+
 // IterateCmds iterates all the commands in the specified range, and calls the
-// callback with the content of each command sequentially.
+// callback with the content and metadata of each command sequentially.
 func (s *dbStore) IterateCmds(from, upto int, f func(Cmd)) error {
 	return s.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucketCmd))
+		metaBucket := tx.Bucket([]byte(bucketCmdMeta))
 		c := b.Cursor()
 		for k, v := c.Seek(marshalSeq(uint64(from))); k != nil && unmarshalSeq(k) < uint64(upto); k, v = c.Next() {
-			f(Cmd{Text: string(v), Seq: int(unmarshalSeq(k))})
+			f(Cmd{Text: string(v), Seq: int(unmarshalSeq(k)), Meta: unmarshalMeta(metaBucket.Get(k))})
 		}
 		return nil
 	})
@@ -147,3 +256,15 @@ func marshalSeq(seq uint64) []byte {
 func unmarshalSeq(key []byte) uint64 {
 	return binary.BigEndian.Uint64(key)
 }
+
+// Unmarshals command metadata, returning nil if it is missing or malformed.
+func unmarshalMeta(data []byte) *CmdMeta {
+	if data == nil {
+		return nil
+	}
+	var meta CmdMeta
+	if json.Unmarshal(data, &meta) != nil {
+		return nil
+	}
+	return &meta
+}
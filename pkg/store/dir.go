@@ -60,6 +60,33 @@ func (s *dbStore) AddDirRaw(d string, score float64) error {
 	})
 }
 
+// MergeDirs merges directories from another directory history into the
+// directory history, and returns the number of directories added or updated.
+// Each directory is given the higher of its existing score and the score in
+// dirs.
+func (s *dbStore) MergeDirs(dirs []Dir) (int, error) {
+	changed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketDir))
+		for _, dir := range dirs {
+			k := []byte(dir.Path)
+			if v := b.Get(k); v != nil && unmarshalScore(v) >= dir.Score {
+				continue
+			}
+			err := b.Put(k, marshalScore(dir.Score))
+			if err != nil {
+				return err
+			}
+			changed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return changed, nil
+}
+
 // DelDir deletes a directory record from history.
 func (s *dbStore) DelDir(d string) error {
 	return s.db.Update(func(tx *bolt.Tx) error {
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"fmt"
+	"os"
+
+	"src.elv.sh/pkg/prog"
+	"src.elv.sh/pkg/store/storeio"
+)
+
+// Program is the subprogram for exporting and importing the database directly,
+// without going through the daemon.
+type Program struct {
+	// Returns the path of the database to use when -db is not given.
+	DefaultDBPath func() (string, error)
+
+	export, imp bool
+	paths       *prog.DaemonPaths
+}
+
+func (p *Program) RegisterFlags(fs *prog.FlagSet) {
+	fs.BoolVar(&p.export, "export-store", false,
+		"Export the command and directory history in the database to stdout")
+	fs.BoolVar(&p.imp, "import-store", false,
+		"Import command and directory history into the database from files or stdin")
+	p.paths = fs.DaemonPaths()
+}
+
+func (p *Program) Run(fds [3]*os.File, args []string) error {
+	if !p.export && !p.imp {
+		return prog.NextProgram()
+	}
+	if p.export && p.imp {
+		return prog.BadUsage("-export-store and -import-store are mutually exclusive")
+	}
+	if p.export && len(args) > 0 {
+		return prog.BadUsage("arguments are not allowed with -export-store")
+	}
+
+	dbPath := p.paths.DB
+	if dbPath == "" {
+		var err error
+		dbPath, err = p.DefaultDBPath()
+		if err != nil {
+			return err
+		}
+	}
+	st, err := NewStore(dbPath)
+	if err != nil {
+		return fmt.Errorf("cannot open database %s (is the daemon running?): %w", dbPath, err)
+	}
+	defer st.Close()
+
+	if p.export {
+		return storeio.Export(fds[1], st)
+	}
+	if len(args) == 0 {
+		return importAndReport(fds, st, "stdin", fds[0])
+	}
+	for _, arg := range args {
+		f, err := os.Open(arg)
+		if err != nil {
+			return err
+		}
+		err = importAndReport(fds, st, arg, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", arg, err)
+		}
+	}
+	return nil
+}
+
+func importAndReport(fds [3]*os.File, st DBStore, name string, f *os.File) error {
+	stats, err := storeio.Import(f, st)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(fds[1], "Imported %d commands and %d directories from %s\n",
+		stats.Cmds, stats.Dirs, name)
+	return nil
+}
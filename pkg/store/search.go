@@ -0,0 +1,211 @@
+package store
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	bolt "go.etcd.io/bbolt"
+	. "src.elv.sh/pkg/store/storedefs"
+)
+
+// Parameters for ranking search results.
+const (
+	SearchMatchWeight     = 3
+	SearchFrequencyWeight = 1
+	SearchRecencyWeight   = 2
+	// Number of commands after which the recency component of the score
+	// halves.
+	SearchRecencyHalfLife = 500
+)
+
+// SearchCmds finds commands matching the query, and returns them ordered from
+// the best match to the worst match.
+//
+// Commands are ranked by a score that is the weighted sum of three components:
+// how well the command matches the query, ranging from 0 to 1; the base-10
+// logarithm of the number of times it appears in the history; and how recently
+// it was run, which starts at 1 and halves every SearchRecencyHalfLife
+// commands. Commands with the same score are ordered from newest to oldest.
+func (s *dbStore) SearchCmds(query string, opts SearchOpts) ([]Cmd, error) {
+	match, err := compileMatcher(query, opts.Mode)
+	if err != nil {
+		return nil, err
+	}
+	var cmds []Cmd
+	err = s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketCmd))
+		// Statistics of each distinct command text, including non-matching
+		// ones, so that each text only needs to be matched once.
+		stats := make(map[string]*textStat)
+		var results []searchResult
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			seq := int(unmarshalSeq(k))
+			st, ok := stats[string(v)]
+			if !ok {
+				text := string(v)
+				st = &textStat{text: text, quality: match(text)}
+				stats[text] = st
+			}
+			if st.quality == 0 {
+				continue
+			}
+			st.count++
+			st.last = seq
+			if !opts.Dedup {
+				results = append(results, searchResult{seq, st, 0})
+			}
+		}
+		if opts.Dedup {
+			for _, st := range stats {
+				if st.quality > 0 {
+					results = append(results, searchResult{st.last, st, 0})
+				}
+			}
+		}
+
+		newest := int(b.Sequence())
+		for i := range results {
+			r := &results[i]
+			age := float64(newest - r.seq)
+			r.score = SearchMatchWeight*r.stat.quality +
+				SearchFrequencyWeight*math.Log10(float64(r.stat.count)) +
+				SearchRecencyWeight*math.Exp2(-age/SearchRecencyHalfLife)
+		}
+		sort.Slice(results, func(i, j int) bool {
+			if results[i].score != results[j].score {
+				return results[i].score > results[j].score
+			}
+			return results[i].seq > results[j].seq
+		})
+		if opts.Limit > 0 && len(results) > opts.Limit {
+			results = results[:opts.Limit]
+		}
+
+		metaBucket := tx.Bucket([]byte(bucketCmdMeta))
+		cmds = make([]Cmd, len(results))
+		for i, r := range results {
+			k := marshalSeq(uint64(r.seq))
+			cmds[i] = Cmd{Text: r.stat.text, Seq: r.seq, Meta: unmarshalMeta(metaBucket.Get(k))}
+		}
+		return nil
+	})
+	return cmds, err
+}
+
+type textStat struct {
+	text    string
+	quality float64
+	count   int
+	// Sequence number of the last instance.
+	last int
+}
+
+type searchResult struct {
+	seq   int
+	stat  *textStat
+	score float64
+}
+
+// A function that returns how well a command matches the query, ranging from 0
+// (not matching) to 1 (matching perfectly).
+type matcher func(text string) float64
+
+func compileMatcher(query string, mode SearchMode) (matcher, error) {
+	switch mode {
+	case SearchRegexp:
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return nil, err
+		}
+		return func(text string) float64 {
+			span := re.FindStringIndex(text)
+			if span == nil {
+				return 0
+			}
+			return coverage(span[1]-span[0], len(text))
+		}, nil
+	case SearchFuzzy:
+		var pattern []rune
+		for _, r := range query {
+			if !unicode.IsSpace(r) {
+				pattern = append(pattern, r)
+			}
+		}
+		ignoreCase := isLower(string(pattern))
+		return func(text string) float64 {
+			if ignoreCase {
+				text = strings.ToLower(text)
+			}
+			return fuzzyMatch([]rune(text), pattern)
+		}, nil
+	default:
+		words := strings.Fields(query)
+		return func(text string) float64 {
+			lower := ""
+			matched := 0
+			for _, word := range words {
+				haystack := text
+				if isLower(word) {
+					if lower == "" {
+						lower = strings.ToLower(text)
+					}
+					haystack = lower
+				}
+				if !strings.Contains(haystack, word) {
+					return 0
+				}
+				matched += len(word)
+			}
+			if len(words) == 0 {
+				return 1
+			}
+			return coverage(matched, len(text))
+		}, nil
+	}
+}
+
+// Returns the score for a match that covers n out of total bytes.
+func coverage(n, total int) float64 {
+	return math.Min(1, float64(1+n)/float64(1+total))
+}
+
+// Matches the pattern as a subsequence of the text, and returns a score based
+// on how tightly the pattern matches: 1 if the matched characters are
+// contiguous, decreasing as the gaps between them grow.
+func fuzzyMatch(text, pattern []rune) float64 {
+	if len(pattern) == 0 {
+		return 1
+	}
+	// Find the earliest end of a match, and then the latest start of a match
+	// ending there, which gives a tight (though not necessarily the tightest)
+	// match.
+	end, j := -1, 0
+	for i, r := range text {
+		if r == pattern[j] {
+			j++
+			if j == len(pattern) {
+				end = i
+				break
+			}
+		}
+	}
+	if end == -1 {
+		return 0
+	}
+	start, j := end, len(pattern)-1
+	for ; ; start-- {
+		if text[start] == pattern[j] {
+			if j == 0 {
+				break
+			}
+			j--
+		}
+	}
+	return float64(len(pattern)) / float64(end-start+1)
+}
+
+func isLower(s string) bool { return s == strings.ToLower(s) }
@@ -0,0 +1,31 @@
+package store_test
+
+import (
+	"fmt"
+	"testing"
+
+	"src.elv.sh/pkg/store"
+	"src.elv.sh/pkg/store/storedefs"
+	"src.elv.sh/pkg/store/storetest"
+)
+
+func TestSearchCmds(t *testing.T) {
+	storetest.TestSearchCmds(t, store.MustTempStore(t))
+}
+
+func BenchmarkSearchCmds(b *testing.B) {
+	s := store.MustTempStore(b)
+	cmds := make([]storedefs.Cmd, 10000)
+	for i := range cmds {
+		cmds[i] = storedefs.Cmd{Text: fmt.Sprintf("echo %d; ls /tmp/dir%d", i, i%100)}
+	}
+	s.MergeCmds(cmds)
+
+	for _, mode := range []storedefs.SearchMode{storedefs.SearchSubstring, storedefs.SearchRegexp, storedefs.SearchFuzzy} {
+		b.Run(mode.String(), func(b *testing.B) {
+			for range b.N {
+				s.SearchCmds("ls dir4", storedefs.SearchOpts{Mode: mode, Dedup: true, Limit: 1000})
+			}
+		})
+	}
+}
@@ -4,7 +4,13 @@
 // does not need to depend on the concrete implementation.
 package storedefs
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"src.elv.sh/pkg/eval/vals"
+)
 
 // NoBlacklist is an empty blacklist, to be used in GetDirs.
 var NoBlacklist = map[string]struct{}{}
@@ -13,6 +19,10 @@ var NoBlacklist = map[string]struct{}{}
 // completes with no result.
 var ErrNoMatchingCmd = errors.New("no matching command line")
 
+// ErrNoCmdMeta is the error returned when a CmdMeta query is made for a command
+// without metadata.
+var ErrNoCmdMeta = errors.New("no metadata for command")
+
 // Store is an interface satisfied by the storage service.
 type Store interface {
 	NextCmdSeq() (int, error)
@@ -22,10 +32,15 @@ type Store interface {
 	CmdsWithSeq(from, upto int) ([]Cmd, error)
 	NextCmd(from int, prefix string) (Cmd, error)
 	PrevCmd(upto int, prefix string) (Cmd, error)
+	SetCmdMeta(seq int, meta CmdMeta) error
+	CmdMeta(seq int) (CmdMeta, error)
+	SearchCmds(query string, opts SearchOpts) ([]Cmd, error)
+	MergeCmds(cmds []Cmd) (int, error)
 
 	AddDir(dir string, incFactor float64) error
 	DelDir(dir string) error
 	Dirs(blacklist map[string]struct{}) ([]Dir, error)
+	MergeDirs(dirs []Dir) (int, error)
 }
 
 // Dir is an entry in the directory history.
@@ -38,4 +53,115 @@ type Dir struct {
 type Cmd struct {
 	Text string
 	Seq  int
+	// Metadata of the command, or nil if not known. Only set by CmdsWithSeq.
+	Meta *CmdMeta
+}
+
+// Map converts the Cmd to a map for Elvish code, with the keys id and cmd, and
+// the keys added by [CmdMeta.AddToMap].
+func (cmd Cmd) Map() vals.Map {
+	return cmd.Meta.AddToMap(vals.MakeMap("id", cmd.Seq, "cmd", cmd.Text))
+}
+
+// CmdMeta keeps metadata about the execution of a command in the command
+// history.
+type CmdMeta struct {
+	// Working directory when the command was started.
+	Dir string
+	// Exit status of the command: 0 if it succeeded; the exit status of the
+	// external command (or 128 plus the signal number if it was killed by a
+	// signal) if it failed because of one; 1 otherwise.
+	Status int
+	// Time when the command was started.
+	Start time.Time
+	// How long the command took to run.
+	Duration time.Duration
+	// Fields that are not known, which is possible for commands imported from
+	// other histories. All fields are known for commands run from the REPL.
+	Unknown CmdMetaFields
+}
+
+// AddToMap adds the fields of the metadata that are known to m, with the keys
+// dir, status, start and duration, and returns the result. The start time is
+// represented as seconds since the Unix epoch, and the duration in seconds. It
+// returns m unchanged if meta is nil.
+func (meta *CmdMeta) AddToMap(m vals.Map) vals.Map {
+	if meta == nil {
+		return m
+	}
+	if meta.Has(CmdMetaDir) {
+		m = m.Assoc("dir", meta.Dir)
+	}
+	if meta.Has(CmdMetaStatus) {
+		m = m.Assoc("status", meta.Status)
+	}
+	if meta.Has(CmdMetaStart) {
+		m = m.Assoc("start", vals.UnixSeconds(meta.Start))
+	}
+	if meta.Has(CmdMetaDuration) {
+		m = m.Assoc("duration", meta.Duration.Seconds())
+	}
+	return m
+}
+
+// CmdMetaFields is a set of fields of CmdMeta.
+type CmdMetaFields uint8
+
+// Possible members of CmdMetaFields.
+const (
+	CmdMetaDir CmdMetaFields = 1 << iota
+	CmdMetaStatus
+	CmdMetaStart
+	CmdMetaDuration
+)
+
+// Has reports whether the field is known.
+func (meta *CmdMeta) Has(field CmdMetaFields) bool {
+	return meta.Unknown&field == 0
+}
+
+// SearchMode determines how the query of SearchCmds is matched against
+// commands.
+type SearchMode int
+
+// Possible values of SearchMode.
+const (
+	// Matches commands that contain all the whitespace-separated words of the
+	// query.
+	SearchSubstring SearchMode = iota
+	// Matches commands that match the query as a regular expression.
+	SearchRegexp
+	// Matches commands that contain all the non-whitespace characters of the
+	// query in order, but not necessarily next to each other.
+	SearchFuzzy
+)
+
+var searchModeNames = [...]string{"substring", "regexp", "fuzzy"}
+
+func (m SearchMode) String() string {
+	if 0 <= m && int(m) < len(searchModeNames) {
+		return searchModeNames[m]
+	}
+	return fmt.Sprintf("SearchMode(%d)", int(m))
+}
+
+// ParseSearchMode parses the name of a search mode, as returned by
+// SearchMode.String.
+func ParseSearchMode(s string) (SearchMode, error) {
+	for i, name := range searchModeNames {
+		if s == name {
+			return SearchMode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid search mode %q; must be one of substring, regexp and fuzzy", s)
+}
+
+// SearchOpts keeps options to SearchCmds.
+type SearchOpts struct {
+	Mode SearchMode
+	// If true, only the most recent instance of each command is included in
+	// the result.
+	Dedup bool
+	// The maximum number of commands to return. Unlimited if zero or negative.
+	Limit int
 }
@@ -0,0 +1,191 @@
+// Package storeio implements exporting and importing the command and directory
+// history in a store.
+//
+// The data is represented in the JSON Lines format, with one JSON object on
+// each line. Objects with "type": "cmd" represent commands, with the following
+// fields:
+//
+//   - "text": The text of the command.
+//   - "dir", "status", "start", "duration": Metadata of the command (see
+//     [storedefs.CmdMeta]); omitted if unknown. The start time is in RFC 3339
+//     format, and the duration is in seconds.
+//
+// Objects with "type": "dir" represent directories, with the following fields:
+//
+//   - "path": The path of the directory.
+//   - "score": The score of the directory.
+//
+// Commands are exported from oldest to newest, followed by directories in
+// decreasing order of score.
+package storeio
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+type typedEntry struct {
+	Type string `json:"type"`
+}
+
+type cmdEntry struct {
+	Type     string     `json:"type"`
+	Text     string     `json:"text"`
+	Dir      *string    `json:"dir,omitempty"`
+	Status   *int       `json:"status,omitempty"`
+	Start    *time.Time `json:"start,omitempty"`
+	Duration *float64   `json:"duration,omitempty"`
+}
+
+type dirEntry struct {
+	Type  string  `json:"type"`
+	Path  string  `json:"path"`
+	Score float64 `json:"score"`
+}
+
+// Export writes all the commands and directories in the store to w.
+func Export(w io.Writer, s storedefs.Store) error {
+	cmds, err := s.CmdsWithSeq(0, -1)
+	if err != nil {
+		return err
+	}
+	dirs, err := s.Dirs(storedefs.NoBlacklist)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	for _, cmd := range cmds {
+		entry := cmdEntry{Type: "cmd", Text: cmd.Text}
+		if meta := cmd.Meta; meta != nil {
+			if meta.Has(storedefs.CmdMetaDir) {
+				entry.Dir = &meta.Dir
+			}
+			if meta.Has(storedefs.CmdMetaStatus) {
+				entry.Status = &meta.Status
+			}
+			if meta.Has(storedefs.CmdMetaStart) {
+				entry.Start = &meta.Start
+			}
+			if meta.Has(storedefs.CmdMetaDuration) {
+				duration := meta.Duration.Seconds()
+				entry.Duration = &duration
+			}
+		}
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	for _, dir := range dirs {
+		if err := enc.Encode(dirEntry{"dir", dir.Path, dir.Score}); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Stats contains the number of entries changed by an import.
+type Stats struct {
+	// Number of commands added.
+	Cmds int
+	// Number of directories added or updated.
+	Dirs int
+}
+
+// Import reads commands and directories from r, and merges them into the
+// store with [storedefs.Store.MergeCmds] and [storedefs.Store.MergeDirs].
+// Nothing is imported if r contains any malformed line.
+func Import(r io.Reader, s storedefs.Store) (Stats, error) {
+	cmds, dirs, err := read(r)
+	if err != nil {
+		return Stats{}, err
+	}
+	return Merge(s, cmds, dirs)
+}
+
+// Merge merges commands and directories into the store.
+func Merge(s storedefs.Store, cmds []storedefs.Cmd, dirs []storedefs.Dir) (Stats, error) {
+	var stats Stats
+	var err error
+	if len(cmds) > 0 {
+		stats.Cmds, err = s.MergeCmds(cmds)
+		if err != nil {
+			return stats, err
+		}
+	}
+	if len(dirs) > 0 {
+		stats.Dirs, err = s.MergeDirs(dirs)
+	}
+	return stats, err
+}
+
+func read(r io.Reader) ([]storedefs.Cmd, []storedefs.Dir, error) {
+	var cmds []storedefs.Cmd
+	var dirs []storedefs.Dir
+	scanner := bufio.NewScanner(r)
+	// Commands can be long; allow lines of up to 16MiB.
+	scanner.Buffer(nil, 16<<20)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var typed typedEntry
+		if err := json.Unmarshal(line, &typed); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		switch typed.Type {
+		case "cmd":
+			var entry cmdEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return nil, nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			cmds = append(cmds, entry.cmd())
+		case "dir":
+			var entry dirEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return nil, nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			dirs = append(dirs, storedefs.Dir{Path: entry.Path, Score: entry.Score})
+		default:
+			return nil, nil, fmt.Errorf("line %d: unknown type %q", lineNo, typed.Type)
+		}
+	}
+	return cmds, dirs, scanner.Err()
+}
+
+func (entry cmdEntry) cmd() storedefs.Cmd {
+	cmd := storedefs.Cmd{Text: entry.Text}
+	if entry.Dir == nil && entry.Status == nil && entry.Start == nil && entry.Duration == nil {
+		return cmd
+	}
+	var meta storedefs.CmdMeta
+	if entry.Dir != nil {
+		meta.Dir = *entry.Dir
+	} else {
+		meta.Unknown |= storedefs.CmdMetaDir
+	}
+	if entry.Status != nil {
+		meta.Status = *entry.Status
+	} else {
+		meta.Unknown |= storedefs.CmdMetaStatus
+	}
+	if entry.Start != nil {
+		meta.Start = *entry.Start
+	} else {
+		meta.Unknown |= storedefs.CmdMetaStart
+	}
+	if entry.Duration != nil {
+		meta.Duration = time.Duration(*entry.Duration * float64(time.Second))
+	} else {
+		meta.Unknown |= storedefs.CmdMetaDuration
+	}
+	cmd.Meta = &meta
+	return cmd
+}
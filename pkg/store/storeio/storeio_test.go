@@ -0,0 +1,119 @@
+package storeio_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"src.elv.sh/pkg/store"
+	"src.elv.sh/pkg/store/storedefs"
+	. "src.elv.sh/pkg/store/storeio"
+)
+
+func TestExport(t *testing.T) {
+	st := store.MustTempStore(t)
+	st.AddCmd("echo foo")
+	seq, _ := st.AddCmd("echo <bar>")
+	st.SetCmdMeta(seq, storedefs.CmdMeta{
+		Dir: "/home/elf", Status: 1,
+		Start:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Duration: 1500 * time.Millisecond})
+	st.AddDir("/home/elf", 1)
+
+	var buf bytes.Buffer
+	err := Export(&buf, st)
+
+	want := `{"type":"cmd","text":"echo foo"}
+{"type":"cmd","text":"echo <bar>","dir":"/home/elf","status":1,"start":"2024-01-02T03:04:05Z","duration":1.5}
+{"type":"dir","path":"/home/elf","score":10}
+`
+	if buf.String() != want || err != nil {
+		t.Errorf("got (%q, %v), want (%q, nil)", buf.String(), err, want)
+	}
+}
+
+func TestImport(t *testing.T) {
+	st := store.MustTempStore(t)
+	st.AddCmd("echo foo")
+
+	stats, err := Import(strings.NewReader(`{"type":"cmd","text":"echo foo"}
+
+{"type":"cmd","text":"echo bar","status":0,"start":"2024-01-02T03:04:05Z"}
+{"type":"dir","path":"/home/elf","score":10}
+`), st)
+
+	if stats != (Stats{Cmds: 1, Dirs: 1}) || err != nil {
+		t.Errorf("got (%v, %v), want ({1 1}, nil)", stats, err)
+	}
+	cmds, _ := st.CmdsWithSeq(0, -1)
+	if len(cmds) != 2 || cmds[1].Text != "echo bar" || cmds[1].Meta == nil ||
+		!cmds[1].Meta.Start.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("got commands %v", cmds)
+	}
+
+	// Fields that were not imported are still omitted when exporting.
+	var buf bytes.Buffer
+	Export(&buf, st)
+	wantLine := `{"type":"cmd","text":"echo bar","status":0,"start":"2024-01-02T03:04:05Z"}`
+	if lines := strings.Split(buf.String(), "\n"); len(lines) < 2 || lines[1] != wantLine {
+		t.Errorf("got exported:\n%s\nwant second line:\n%s", &buf, wantLine)
+	}
+}
+
+func TestImport_RoundTrip(t *testing.T) {
+	st := store.MustTempStore(t)
+	st.AddCmd("echo foo")
+	st.AddCmd("echo foo")
+	seq, _ := st.AddCmd("ls")
+	st.SetCmdMeta(seq, storedefs.CmdMeta{Dir: "/", Start: time.Unix(1700000000, 0)})
+	st.AddDir("/home/elf", 1)
+	var exported bytes.Buffer
+	Export(&exported, st)
+
+	st2 := store.MustTempStore(t)
+	stats, err := Import(bytes.NewReader(exported.Bytes()), st2)
+	if stats != (Stats{Cmds: 2, Dirs: 1}) || err != nil {
+		t.Errorf("got (%v, %v), want ({2 1}, nil)", stats, err)
+	}
+	var reexported bytes.Buffer
+	Export(&reexported, st2)
+	// Commands without a start time can't be told apart from commands imported
+	// before, so only one "echo foo" is imported.
+	want := strings.Replace(exported.String(), "{\"type\":\"cmd\",\"text\":\"echo foo\"}\n", "", 1)
+	if reexported.String() != want {
+		t.Errorf("got re-exported:\n%s\nwant:\n%s", &reexported, want)
+	}
+
+	// Importing again is a no-op.
+	stats, err = Import(bytes.NewReader(exported.Bytes()), st2)
+	if stats != (Stats{}) || err != nil {
+		t.Errorf("got (%v, %v), want ({0 0}, nil)", stats, err)
+	}
+}
+
+var importErrorTests = []struct {
+	name    string
+	data    string
+	wantErr string
+}{
+	{"invalid JSON", "{\"type\":\"cmd\",\"text\":\"ls\"}\n{", "line 2: unexpected end of JSON input"},
+	{"unknown type", `{"type":"foo"}`, `line 1: unknown type "foo"`},
+	{"wrong field type", `{"type":"dir","path":"/","score":"x"}`,
+		"line 1: json: cannot unmarshal string into Go struct field dirEntry.score of type float64"},
+}
+
+func TestImport_Errors(t *testing.T) {
+	for _, tc := range importErrorTests {
+		t.Run(tc.name, func(t *testing.T) {
+			st := store.MustTempStore(t)
+			_, err := Import(strings.NewReader(tc.data), st)
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("got error %v, want %q", err, tc.wantErr)
+			}
+			if cmds, _ := st.CmdsWithSeq(0, -1); len(cmds) != 0 {
+				t.Errorf("got commands %v imported, want none", cmds)
+			}
+		})
+	}
+}
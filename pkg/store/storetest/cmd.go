@@ -3,6 +3,7 @@ package storetest
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"src.elv.sh/pkg/store/storedefs"
 )
@@ -96,6 +97,29 @@ func TestCmd(t *testing.T, store storedefs.Store) {
 		}
 	}
 
+	// SetCmdMeta and CmdMeta
+	meta := storedefs.CmdMeta{
+		Dir: "/home/elf", Status: 2,
+		Start:    time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+		Duration: 1500 * time.Millisecond,
+	}
+	if err := store.SetCmdMeta(1, meta); err != nil {
+		t.Errorf("store.SetCmdMeta(1, %v) => %v, want nil", meta, err)
+	}
+	if got, err := store.CmdMeta(1); !equalMeta(got, meta) || err != nil {
+		t.Errorf("store.CmdMeta(1) => (%v, %v), want (%v, nil)", got, err, meta)
+	}
+	if cmds, err := store.CmdsWithSeq(1, 3); len(cmds) != 2 || err != nil ||
+		cmds[0].Meta == nil || !equalMeta(*cmds[0].Meta, meta) || cmds[1].Meta != nil {
+		t.Errorf("store.CmdsWithSeq(1, 3) => (%v, %v), want metadata on first command only", cmds, err)
+	}
+	if _, err := store.CmdMeta(2); !matchErr(err, storedefs.ErrNoCmdMeta) {
+		t.Errorf("store.CmdMeta(2) => error %v, want %v", err, storedefs.ErrNoCmdMeta)
+	}
+	if err := store.SetCmdMeta(100, meta); !matchErr(err, storedefs.ErrNoMatchingCmd) {
+		t.Errorf("store.SetCmdMeta(100, ...) => %v, want %v", err, storedefs.ErrNoMatchingCmd)
+	}
+
 	// DelCmd
 	if err := store.DelCmd(1); err != nil {
 		t.Error("Failed to remove cmd")
@@ -104,6 +128,13 @@ func TestCmd(t *testing.T, store storedefs.Store) {
 		t.Errorf("Cmd(1) => (%v, %v), want (%v, %v)",
 			seq, err, "", storedefs.ErrNoMatchingCmd)
 	}
+	if _, err := store.CmdMeta(1); !matchErr(err, storedefs.ErrNoCmdMeta) {
+		t.Errorf("CmdMeta(1) after DelCmd(1) => error %v, want %v", err, storedefs.ErrNoCmdMeta)
+	}
+}
+
+func equalMeta(a, b storedefs.CmdMeta) bool {
+	return a.Dir == b.Dir && a.Status == b.Status && a.Start.Equal(b.Start) && a.Duration == b.Duration
 }
 
 func equalCmds(a, b []storedefs.Cmd) bool {
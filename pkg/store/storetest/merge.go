@@ -0,0 +1,66 @@
+package storetest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+// TestMerge tests the merging functionality of a Store.
+func TestMerge(t *testing.T, store storedefs.Store) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	meta := &storedefs.CmdMeta{Dir: "/tmp", Start: start, Duration: time.Second}
+	seq, _ := store.AddCmd("merge a")
+	store.SetCmdMeta(seq, *meta)
+	store.AddCmd("merge b")
+
+	added, err := store.MergeCmds([]storedefs.Cmd{
+		// Skipped: same text and start time.
+		{Text: "merge a", Meta: meta},
+		// Added: same text, different start time.
+		{Text: "merge a", Meta: &storedefs.CmdMeta{Start: start.Add(time.Hour)}},
+		// Skipped: same text and no start time.
+		{Text: "merge b"},
+		// Added: new.
+		{Text: "merge c"},
+		// Skipped: same as commands merged above.
+		{Text: "merge c"},
+		{Text: "merge a", Meta: &storedefs.CmdMeta{Start: start.Add(time.Hour)}},
+	})
+	if added != 2 || err != nil {
+		t.Errorf("store.MergeCmds(...) => (%v, %v), want (2, nil)", added, err)
+	}
+	cmds, _ := store.CmdsWithSeq(seq, -1)
+	var texts []string
+	for _, cmd := range cmds {
+		texts = append(texts, cmd.Text)
+	}
+	wantTexts := []string{"merge a", "merge b", "merge a", "merge c"}
+	if !reflect.DeepEqual(texts, wantTexts) {
+		t.Errorf("got commands %q after merging, want %q", texts, wantTexts)
+	}
+	if len(cmds) == len(wantTexts) && (cmds[2].Meta == nil || !cmds[2].Meta.Start.Equal(start.Add(time.Hour))) {
+		t.Errorf("got metadata %v for merged command, want start time %v", cmds[2].Meta, start.Add(time.Hour))
+	}
+
+	store.AddDir("/merge/a", 1)
+	store.AddDir("/merge/b", 1)
+	changed, err := store.MergeDirs([]storedefs.Dir{
+		{Path: "/merge/a", Score: 100},
+		{Path: "/merge/b", Score: 1},
+		{Path: "/merge/c", Score: 2},
+	})
+	if changed != 2 || err != nil {
+		t.Errorf("store.MergeDirs(...) => (%v, %v), want (2, nil)", changed, err)
+	}
+	dirs, _ := store.Dirs(storedefs.NoBlacklist)
+	scores := make(map[string]float64)
+	for _, dir := range dirs {
+		scores[dir.Path] = dir.Score
+	}
+	if scores["/merge/a"] != 100 || scores["/merge/b"] < 1 || scores["/merge/c"] != 2 {
+		t.Errorf("got dirs %v after merging", dirs)
+	}
+}
@@ -0,0 +1,63 @@
+package storetest
+
+import (
+	"slices"
+	"testing"
+
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+var (
+	cmdsToSearch = []string{
+		"git status", "git commit -m fix", "git status", "echo GIT", "grep -r todo"}
+	searchTests = []struct {
+		query     string
+		opts      storedefs.SearchOpts
+		wantTexts []string
+	}{
+		// Ranking combines how well the command matches the query, frequency
+		// and recency.
+		{"git", storedefs.SearchOpts{Dedup: true},
+			[]string{"git status", "echo GIT", "git commit -m fix"}},
+		{"git", storedefs.SearchOpts{Dedup: true, Limit: 1},
+			[]string{"git status"}},
+		{"git status", storedefs.SearchOpts{},
+			[]string{"git status", "git status"}},
+		// Smart case.
+		{"GIT", storedefs.SearchOpts{Dedup: true}, []string{"echo GIT"}},
+		// All words need to match.
+		{"git fix", storedefs.SearchOpts{Dedup: true}, []string{"git commit -m fix"}},
+
+		{"^git (s|c)", storedefs.SearchOpts{Mode: storedefs.SearchRegexp, Dedup: true},
+			[]string{"git status", "git commit -m fix"}},
+
+		{"gst", storedefs.SearchOpts{Mode: storedefs.SearchFuzzy, Dedup: true},
+			[]string{"git status"}},
+		{"gt", storedefs.SearchOpts{Mode: storedefs.SearchFuzzy, Dedup: true},
+			[]string{"git status", "echo GIT", "git commit -m fix", "grep -r todo"}},
+	}
+)
+
+// TestSearchCmds tests the command history search functionality of a Store.
+func TestSearchCmds(t *testing.T, store storedefs.Store) {
+	for _, cmd := range cmdsToSearch {
+		store.AddCmd(cmd)
+	}
+
+	for _, tc := range searchTests {
+		cmds, err := store.SearchCmds(tc.query, tc.opts)
+		texts := make([]string, len(cmds))
+		for i, cmd := range cmds {
+			texts[i] = cmd.Text
+		}
+		if !slices.Equal(texts, tc.wantTexts) || err != nil {
+			t.Errorf("store.SearchCmds(%q, %+v) => (%q, %v), want (%q, nil)",
+				tc.query, tc.opts, texts, err, tc.wantTexts)
+		}
+	}
+
+	_, err := store.SearchCmds("(", storedefs.SearchOpts{Mode: storedefs.SearchRegexp})
+	if err == nil {
+		t.Errorf("store.SearchCmds with invalid regexp returns nil error")
+	}
+}
@@ -0,0 +1,21 @@
+package store_test
+
+import (
+	"embed"
+	"errors"
+	"testing"
+
+	"src.elv.sh/pkg/eval/evaltest"
+	"src.elv.sh/pkg/prog/progtest"
+	"src.elv.sh/pkg/store"
+)
+
+//go:embed *.elvts
+var transcripts embed.FS
+
+func TestTranscripts(t *testing.T) {
+	evaltest.TestTranscriptsInFS(t, transcripts,
+		"elvish-in-global", progtest.ElvishInGlobal(&store.Program{
+			DefaultDBPath: func() (string, error) { return "", errors.New("no default db") }}),
+	)
+}
@@ -13,3 +13,5 @@ func notifySignals() chan os.Signal {
 	signal.Notify(sigCh)
 	return sigCh
 }
+
+func withSIGTSTPCaught(f func()) { f() }
@@ -5,21 +5,41 @@ package sys
 import (
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 )
 
+// Protects the disposition of SIGTSTP, which is ignored by notifySignals and
+// caught by withSIGTSTPCaught.
+var sigtstpMutex sync.Mutex
+
 func notifySignals() chan os.Signal {
+	sigtstpMutex.Lock()
+	defer sigtstpMutex.Unlock()
 	// This catches every signal regardless of whether it is ignored.
 	sigCh := make(chan os.Signal, sigsChanBufferSize)
 	signal.Notify(sigCh)
 	// Calling signal.Notify will reset the signal ignore status, so we need to
 	// call signal.Ignore every time we call signal.Notify.
 	//
-	// TODO: Remove this if, and when, job control is implemented. This
-	// handles the case of running an external command from an interactive
-	// prompt.
+	// This handles the case of running an external command from an
+	// interactive prompt without job control. When job control is enabled,
+	// external commands are started with withSIGTSTPCaught, so that they don't
+	// inherit the ignored status and can be stopped with Ctrl-Z.
 	//
 	// See https://b.elv.sh/988.
 	signal.Ignore(syscall.SIGTTIN, syscall.SIGTTOU, syscall.SIGTSTP)
 	return sigCh
 }
+
+// Nothing reads from this channel; it only exists to catch SIGTSTP.
+var sigtstpCh = make(chan os.Signal, 1)
+
+// Processes started by f inherit ignored signals but not signal handlers, so
+// catching SIGTSTP makes them stoppable while Elvish itself is not stopped.
+func withSIGTSTPCaught(f func()) {
+	sigtstpMutex.Lock()
+	defer sigtstpMutex.Unlock()
+	signal.Notify(sigtstpCh, syscall.SIGTSTP)
+	f()
+}
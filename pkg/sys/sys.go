@@ -14,6 +14,12 @@ const sigsChanBufferSize = 256
 // NotifySignals returns a channel on which all signals gets delivered.
 func NotifySignals() chan os.Signal { return notifySignals() }
 
+// WithSIGTSTPCaught calls f with SIGTSTP caught rather than ignored, so that
+// processes started by f can be stopped with SIGTSTP. Concurrent calls to
+// NotifySignals, which ignores SIGTSTP, are blocked until f returns. This is a
+// nop on non-Unix systems.
+func WithSIGTSTPCaught(f func()) { withSIGTSTPCaught(f) }
+
 // SIGWINCH is the window size change signal.
 const SIGWINCH = sigWINCH
 